@@ -0,0 +1,118 @@
+//go:build !solution
+
+package lrucache
+
+// node is an element of the intrusive recency list, so entries are stored
+// without boxing into interface{}.
+type node[K comparable, V any] struct {
+	key   K
+	value V
+	prev  *node[K, V]
+	next  *node[K, V]
+}
+
+// GenericCache is an LRU cache with arbitrary keys and values. It has the
+// same semantics as LRUCache.
+type GenericCache[K comparable, V any] struct {
+	capacity int
+	// root is the sentinel of the circular list, root.next is the least
+	// recently used entry
+	root   node[K, V]
+	access map[K]*node[K, V]
+}
+
+func NewGeneric[K comparable, V any](capacity int) *GenericCache[K, V] {
+	c := &GenericCache[K, V]{
+		capacity: capacity,
+		access:   map[K]*node[K, V]{},
+	}
+	c.root.prev = &c.root
+	c.root.next = &c.root
+	return c
+}
+
+func (c *GenericCache[K, V]) Get(key K) (V, bool) {
+
+	// Check for existence
+	n, ok := c.access[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+
+	// Update the access time
+	c.moveToBack(n)
+	return n.value, true
+}
+
+func (c *GenericCache[K, V]) Set(key K, value V) {
+
+	// If capacity is 0 do nothing
+	if c.capacity == 0 {
+		return
+	}
+
+	// If the key is present just update the value
+	if n, ok := c.access[key]; ok {
+		n.value = value
+		c.moveToBack(n)
+		return
+	}
+
+	// If the key is missing add the entry to the data and check the capacity
+	n := &node[K, V]{key: key, value: value}
+	c.pushBack(n)
+	c.access[key] = n
+	if c.capacity < len(c.access) {
+		front := c.root.next
+		c.remove(front)
+		delete(c.access, front.key)
+	}
+}
+
+func (c *GenericCache[K, V]) Range(f func(key K, value V) bool) {
+	for n := c.root.next; n != &c.root; n = n.next {
+		if !f(n.key, n.value) {
+			return
+		}
+	}
+}
+
+func (c *GenericCache[K, V]) Clear() {
+	c.root.prev = &c.root
+	c.root.next = &c.root
+	c.access = map[K]*node[K, V]{}
+}
+
+func (c *GenericCache[K, V]) Len() int {
+	return len(c.access)
+}
+
+func (c *GenericCache[K, V]) removeKey(key K) {
+	if n, ok := c.access[key]; ok {
+		c.remove(n)
+		delete(c.access, key)
+	}
+}
+
+func (c *GenericCache[K, V]) pushBack(n *node[K, V]) {
+	n.prev = c.root.prev
+	n.next = &c.root
+	n.prev.next = n
+	c.root.prev = n
+}
+
+func (c *GenericCache[K, V]) remove(n *node[K, V]) {
+	n.prev.next = n.next
+	n.next.prev = n.prev
+	n.prev = nil
+	n.next = nil
+}
+
+func (c *GenericCache[K, V]) moveToBack(n *node[K, V]) {
+	if c.root.prev == n {
+		return
+	}
+	c.remove(n)
+	c.pushBack(n)
+}
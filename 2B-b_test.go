@@ -0,0 +1,100 @@
+//go:build !solution
+
+package lrucache
+
+import (
+	"math/rand"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+const (
+	benchmarkCapacity = 1 << 10
+	benchmarkKeySpace = 4 * benchmarkCapacity
+)
+
+// benchmarkKeys is the random workload shared by all benchmarks.
+var benchmarkKeys = func() []int {
+	keys := make([]int, 1<<16)
+	for i := range keys {
+		keys[i] = rand.Intn(benchmarkKeySpace)
+	}
+	return keys
+}()
+
+func BenchmarkLRUCacheSet(b *testing.B) {
+	b.ReportAllocs()
+	c := New(benchmarkCapacity)
+	for i := 0; i < b.N; i++ {
+		c.Set(benchmarkKeys[i%len(benchmarkKeys)], i)
+	}
+}
+
+func BenchmarkGenericCacheSet(b *testing.B) {
+	b.ReportAllocs()
+	c := NewGeneric[int, int](benchmarkCapacity)
+	for i := 0; i < b.N; i++ {
+		c.Set(benchmarkKeys[i%len(benchmarkKeys)], i)
+	}
+}
+
+func BenchmarkLRUCacheGet(b *testing.B) {
+	b.ReportAllocs()
+	c := New(benchmarkCapacity)
+	for i := 0; i < benchmarkKeySpace; i++ {
+		c.Set(i, i)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Get(benchmarkKeys[i%len(benchmarkKeys)])
+	}
+}
+
+func BenchmarkGenericCacheGet(b *testing.B) {
+	b.ReportAllocs()
+	c := NewGeneric[int, int](benchmarkCapacity)
+	for i := 0; i < benchmarkKeySpace; i++ {
+		c.Set(i, i)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Get(benchmarkKeys[i%len(benchmarkKeys)])
+	}
+}
+
+// BenchmarkLockedLRUCacheParallel is the baseline of the sharded cache, a
+// single mutex around LRUCache.
+func BenchmarkLockedLRUCacheParallel(b *testing.B) {
+	var mutex sync.Mutex
+	c := New(benchmarkCapacity)
+	runParallel(b, func(key int) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if _, ok := c.Get(key); !ok {
+			c.Set(key, key)
+		}
+	})
+}
+
+func BenchmarkShardedCacheParallel(b *testing.B) {
+	c := NewSharded(benchmarkCapacity, 4*runtime.GOMAXPROCS(0))
+	runParallel(b, func(key int) {
+		if _, ok := c.Get(key); !ok {
+			c.Set(key, key)
+		}
+	})
+}
+
+func runParallel(b *testing.B, f func(key int)) {
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		i := rand.Intn(len(benchmarkKeys))
+		for pb.Next() {
+			f(benchmarkKeys[i%len(benchmarkKeys)])
+			i++
+		}
+	})
+}
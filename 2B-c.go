@@ -0,0 +1,105 @@
+//go:build !solution
+
+package lrucache
+
+import (
+	"sync"
+)
+
+type shard struct {
+	mutex sync.Mutex
+	cache *LRUCache
+}
+
+// ShardedCache is a concurrent cache spreading keys over independently
+// locked LRU segments. Eviction is LRU within a segment, and Range visits
+// segments one after another.
+type ShardedCache struct {
+	shards []*shard
+}
+
+var _ Cache = (*ShardedCache)(nil)
+
+// NewSharded splits capacity between at most shards segments. The options
+// apply to every segment, with the cost bound split like capacity. Eviction
+// callbacks run under the segment lock and must not use the cache.
+func NewSharded(capacity, shards int, opts ...Option) Cache {
+	// Every shard must be able to hold an entry, or its keys would be dropped
+	limit := int64(capacity)
+	if probe := New(capacity, opts...).(*LRUCache); probe.cost != nil {
+		limit = probe.maxCost
+	}
+	if int64(shards) > limit {
+		shards = int(limit)
+	}
+	if shards < 1 {
+		shards = 1
+	}
+
+	c := &ShardedCache{shards: make([]*shard, shards)}
+	for i := range c.shards {
+		shardCapacity := capacity / shards
+		if i < capacity%shards {
+			shardCapacity++
+		}
+		cache := New(shardCapacity, opts...).(*LRUCache)
+		maxCost := cache.maxCost / int64(shards)
+		if int64(i) < cache.maxCost%int64(shards) {
+			maxCost++
+		}
+		cache.maxCost = maxCost
+		c.shards[i] = &shard{cache: cache}
+	}
+
+	return c
+}
+
+func (c *ShardedCache) shardFor(key int) *shard {
+	// Fibonacci hashing spreads sequential keys over the shards
+	hash := uint64(key) * 0x9E3779B97F4A7C15
+	return c.shards[(hash>>32)%uint64(len(c.shards))]
+}
+
+func (c *ShardedCache) Get(key int) (int, bool) {
+	s := c.shardFor(key)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.cache.Get(key)
+}
+
+func (c *ShardedCache) Set(key, value int) {
+	s := c.shardFor(key)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.cache.Set(key, value)
+}
+
+// Range calls f on a copy of each segment, so f may use the cache.
+func (c *ShardedCache) Range(f func(key, value int) bool) {
+	for _, s := range c.shards {
+		var entries []Entry
+
+		s.mutex.Lock()
+		s.cache.Range(func(key, value int) bool {
+			entries = append(entries, Entry{key: key, value: value})
+			return true
+		})
+		s.mutex.Unlock()
+
+		for _, entry := range entries {
+			if !f(entry.key, entry.value) {
+				return
+			}
+		}
+	}
+}
+
+func (c *ShardedCache) Clear() {
+	for _, s := range c.shards {
+		s.mutex.Lock()
+		s.cache.Clear()
+		s.mutex.Unlock()
+	}
+}
@@ -0,0 +1,92 @@
+//go:build !solution
+
+package lrucache
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShardedCacheKeepsKeysWithFewEntriesPerShard(t *testing.T) {
+	c := NewSharded(2, 8)
+	c.Set(1, 1)
+	c.Set(2, 2)
+
+	for key := 1; key <= 2; key++ {
+		if value, ok := c.Get(key); !ok || value != key {
+			t.Fatalf("Get(%d) = %d, %v", key, value, ok)
+		}
+	}
+}
+
+func TestShardedCacheCapacity(t *testing.T) {
+	c := NewSharded(100, 8)
+	for i := 0; i < 1000; i++ {
+		c.Set(i, i)
+	}
+
+	count := 0
+	c.Range(func(key, value int) bool {
+		if key != value {
+			t.Fatalf("key %d has value %d", key, value)
+		}
+		count++
+		return true
+	})
+	if count == 0 || count > 100 {
+		t.Fatalf("cache holds %d entries", count)
+	}
+}
+
+func TestShardedCacheConcurrent(t *testing.T) {
+	c := NewSharded(64, 8, WithTTL(time.Millisecond)).(*ShardedCache)
+	stop := c.StartJanitor(time.Millisecond)
+	defer stop()
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+
+			for i := 0; i < 2000; i++ {
+				key := (worker*31 + i) % 256
+				switch i % 100 {
+				case 0:
+					c.Clear()
+				case 1:
+					// Range works on a copy, so f may use the cache
+					c.Range(func(key, value int) bool {
+						c.Get(key)
+						return true
+					})
+				case 2:
+					c.SetWithTTL(key, key, 0)
+				default:
+					c.Set(key, key)
+					if value, ok := c.Get(key); ok && value != key {
+						t.Errorf("Get(%d) = %d", key, value)
+					}
+				}
+			}
+		}(worker)
+	}
+	wg.Wait()
+}
+
+func TestShardedCacheJanitorRemovesExpired(t *testing.T) {
+	c := NewSharded(16, 4, WithTTL(time.Millisecond)).(*ShardedCache)
+	for i := 0; i < 16; i++ {
+		c.Set(i, i)
+	}
+
+	stop := c.StartJanitor(time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	stop()
+	stop()
+
+	if removed := c.RemoveExpired(); removed != 0 {
+		t.Fatalf("janitor left %d expired entries", removed)
+	}
+}
@@ -0,0 +1,102 @@
+//go:build !solution
+
+package lrucache
+
+import (
+	"sync"
+	"time"
+)
+
+// TTLCache is a Cache whose entries may expire.
+type TTLCache interface {
+	Cache
+	SetWithTTL(key, value int, ttl time.Duration)
+	RemoveExpired() int
+}
+
+var (
+	_ TTLCache = (*LRUCache)(nil)
+	_ TTLCache = (*ShardedCache)(nil)
+)
+
+type Option func(*LRUCache)
+
+// WithTTL makes Set expire entries after ttl.
+func WithTTL(ttl time.Duration) Option {
+	return func(c *LRUCache) {
+		c.ttl = ttl
+	}
+}
+
+// WithClock replaces time.Now, so expiration can be tested deterministically.
+func WithClock(now func() time.Time) Option {
+	return func(c *LRUCache) {
+		c.now = now
+	}
+}
+
+func (c *LRUCache) expired(entry Entry) bool {
+	return !entry.expiresAt.IsZero() && !c.now().Before(entry.expiresAt)
+}
+
+// RemoveExpired deletes expired entries and returns how many were deleted.
+// Expired entries are never returned anyway, this only frees their memory.
+func (c *LRUCache) RemoveExpired() int {
+	removed := 0
+	for element := c.cache.Front(); element != nil; {
+		next := element.Next()
+		if c.expired(element.Value.(Entry)) {
+			c.removeElement(element, EvictedExpired)
+			removed++
+		}
+		element = next
+	}
+	return removed
+}
+
+func (c *ShardedCache) SetWithTTL(key, value int, ttl time.Duration) {
+	s := c.shardFor(key)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.cache.SetWithTTL(key, value, ttl)
+}
+
+func (c *ShardedCache) RemoveExpired() int {
+	removed := 0
+	for _, s := range c.shards {
+		s.mutex.Lock()
+		removed += s.cache.RemoveExpired()
+		s.mutex.Unlock()
+	}
+	return removed
+}
+
+// StartJanitor removes expired entries every interval until stop is called.
+// stop waits for the janitor to exit.
+// LRUCache is not safe for concurrent use, so the janitor is only offered by
+// the sharded cache.
+func (c *ShardedCache) StartJanitor(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer close(finished)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.RemoveExpired()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+		<-finished
+	}
+}
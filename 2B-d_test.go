@@ -0,0 +1,109 @@
+//go:build !solution
+
+package lrucache
+
+import (
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func TestGetExpiresLazily(t *testing.T) {
+	clock := newFakeClock()
+
+	var evicted []EvictionReason
+	c := New(10, WithTTL(time.Minute), WithClock(clock.Now), WithOnEvict(func(key, value int, reason EvictionReason) {
+		evicted = append(evicted, reason)
+	})).(*LRUCache)
+	c.Set(1, 1)
+
+	clock.Advance(time.Minute - time.Nanosecond)
+	if value, ok := c.Get(1); !ok || value != 1 {
+		t.Fatalf("Get(1) = %d, %v before the ttl", value, ok)
+	}
+
+	// The entry expires exactly at the ttl and is removed by the Get
+	clock.Advance(time.Nanosecond)
+	if value, ok := c.Get(1); ok {
+		t.Fatalf("Get(1) = %d after the ttl", value)
+	}
+	if len(c.access) != 0 || c.cache.Len() != 0 {
+		t.Fatalf("expired entry is still stored")
+	}
+	if len(evicted) != 1 || evicted[0] != EvictedExpired {
+		t.Fatalf("evicted with %v", evicted)
+	}
+}
+
+func TestSetRefreshesTTL(t *testing.T) {
+	clock := newFakeClock()
+	c := New(10, WithTTL(time.Minute), WithClock(clock.Now))
+	c.Set(1, 1)
+
+	clock.Advance(30 * time.Second)
+	c.Set(1, 2)
+
+	clock.Advance(45 * time.Second)
+	if value, ok := c.Get(1); !ok || value != 2 {
+		t.Fatalf("Get(1) = %d, %v", value, ok)
+	}
+}
+
+func TestRangeSkipsExpired(t *testing.T) {
+	clock := newFakeClock()
+	c := New(10, WithClock(clock.Now)).(*LRUCache)
+	c.SetWithTTL(1, 1, time.Second)
+	c.SetWithTTL(2, 2, time.Minute)
+	c.SetWithTTL(3, 3, time.Second)
+	c.Set(4, 4)
+
+	clock.Advance(time.Second)
+
+	var keys []int
+	c.Range(func(key, value int) bool {
+		keys = append(keys, key)
+		return true
+	})
+	if len(keys) != 2 || keys[0] != 2 || keys[1] != 4 {
+		t.Fatalf("Range visited %v", keys)
+	}
+
+	// Range only skips expired entries, RemoveExpired deletes them
+	if c.cache.Len() != 4 {
+		t.Fatalf("Range removed entries, %d left", c.cache.Len())
+	}
+	if removed := c.RemoveExpired(); removed != 2 {
+		t.Fatalf("RemoveExpired = %d", removed)
+	}
+}
+
+func TestSetWithTTLNotPositiveNeverExpires(t *testing.T) {
+	for _, ttl := range []time.Duration{0, -time.Second} {
+		clock := newFakeClock()
+		c := New(10, WithTTL(time.Second), WithClock(clock.Now)).(*LRUCache)
+		c.SetWithTTL(1, 1, ttl)
+
+		clock.Advance(365 * 24 * time.Hour)
+		if value, ok := c.Get(1); !ok || value != 1 {
+			t.Fatalf("ttl %v: Get(1) = %d, %v", ttl, value, ok)
+		}
+		if removed := c.RemoveExpired(); removed != 0 {
+			t.Fatalf("ttl %v: RemoveExpired = %d", ttl, removed)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+//go:build !solution
+
+package lrucache
+
+import (
+	"container/list"
+)
+
+// EvictionReason tells why an entry left the cache.
+type EvictionReason int
+
+const (
+	// EvictedCapacity entries were least recently used when the cache
+	// overflowed.
+	EvictedCapacity EvictionReason = iota
+	// EvictedExpired entries outlived their TTL.
+	EvictedExpired
+	// EvictedCleared entries were removed by Clear.
+	EvictedCleared
+	// EvictedRejected values were never stored because they cost more than
+	// the whole cache.
+	EvictedRejected
+)
+
+func (r EvictionReason) String() string {
+	switch r {
+	case EvictedCapacity:
+		return "capacity"
+	case EvictedExpired:
+		return "expired"
+	case EvictedCleared:
+		return "cleared"
+	case EvictedRejected:
+		return "rejected"
+	default:
+		return "unknown"
+	}
+}
+
+// WithOnEvict calls f for every entry removed by the cache itself. Entries
+// overwritten by Set are not reported.
+func WithOnEvict(f func(key, value int, reason EvictionReason)) Option {
+	return func(c *LRUCache) {
+		c.onEvict = f
+	}
+}
+
+// WithMaxCost bounds the total cost of the entries instead of their number,
+// the capacity passed to New is then ignored.
+func WithMaxCost(maxCost int64, cost func(key, value int) int64) Option {
+	return func(c *LRUCache) {
+		c.maxCost = maxCost
+		c.cost = cost
+	}
+}
+
+func (c *LRUCache) overCapacity() bool {
+	if c.cost != nil {
+		return c.totalCost > c.maxCost
+	}
+	return c.cache.Len() > c.capacity
+}
+
+func (c *LRUCache) removeElement(element *list.Element, reason EvictionReason) {
+	entry := element.Value.(Entry)
+	delete(c.access, entry.key)
+	c.cache.Remove(element)
+	c.totalCost -= entry.cost
+	c.stats.evictions.Add(1)
+
+	if c.onEvict != nil {
+		c.onEvict(entry.key, entry.value, reason)
+	}
+}
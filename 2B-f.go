@@ -0,0 +1,123 @@
+//go:build !solution
+
+package lrucache
+
+import (
+	"container/list"
+	"sort"
+)
+
+type lfuEntry struct {
+	key       int
+	value     int
+	frequency int
+}
+
+// LFUCache evicts the least frequently used entry, breaking ties by recency.
+// Entries are kept in per-frequency lists, so every operation is O(1).
+type LFUCache struct {
+	capacity     int
+	access       map[int]*list.Element
+	frequencies  map[int]*list.List
+	minFrequency int
+}
+
+var _ Cache = (*LFUCache)(nil)
+
+func NewLFU(capacity int) Cache {
+	return &LFUCache{
+		capacity:    capacity,
+		access:      map[int]*list.Element{},
+		frequencies: map[int]*list.List{},
+	}
+}
+
+func (c *LFUCache) Get(key int) (int, bool) {
+
+	// Check for existence
+	element, ok := c.access[key]
+	if !ok {
+		return 0, false
+	}
+
+	c.touch(element)
+	return element.Value.(*lfuEntry).value, true
+}
+
+func (c *LFUCache) Set(key, value int) {
+
+	// If capacity is not positive do nothing
+	if c.capacity <= 0 {
+		return
+	}
+
+	// If the key is present update the value and count the access
+	if element, ok := c.access[key]; ok {
+		element.Value.(*lfuEntry).value = value
+		c.touch(element)
+		return
+	}
+
+	// Make room by dropping the oldest of the least frequent entries
+	if len(c.access) == c.capacity {
+		bucket := c.frequencies[c.minFrequency]
+		c.remove(bucket.Front(), bucket)
+	}
+
+	c.access[key] = c.bucket(1).PushBack(&lfuEntry{key: key, value: value, frequency: 1})
+	c.minFrequency = 1
+}
+
+// Range visits entries from the least to the most frequently used.
+func (c *LFUCache) Range(f func(key, value int) bool) {
+	frequencies := make([]int, 0, len(c.frequencies))
+	for frequency := range c.frequencies {
+		frequencies = append(frequencies, frequency)
+	}
+	sort.Ints(frequencies)
+
+	for _, frequency := range frequencies {
+		for element := c.frequencies[frequency].Front(); element != nil; element = element.Next() {
+			entry := element.Value.(*lfuEntry)
+			if !f(entry.key, entry.value) {
+				return
+			}
+		}
+	}
+}
+
+func (c *LFUCache) Clear() {
+	c.access = map[int]*list.Element{}
+	c.frequencies = map[int]*list.List{}
+	c.minFrequency = 0
+}
+
+// touch moves the entry to the list of the next frequency.
+func (c *LFUCache) touch(element *list.Element) {
+	entry := element.Value.(*lfuEntry)
+	bucket := c.frequencies[entry.frequency]
+	c.remove(element, bucket)
+	if entry.frequency == c.minFrequency && bucket.Len() == 0 {
+		c.minFrequency++
+	}
+
+	entry.frequency++
+	c.access[entry.key] = c.bucket(entry.frequency).PushBack(entry)
+}
+
+func (c *LFUCache) bucket(frequency int) *list.List {
+	bucket, ok := c.frequencies[frequency]
+	if !ok {
+		bucket = list.New()
+		c.frequencies[frequency] = bucket
+	}
+	return bucket
+}
+
+func (c *LFUCache) remove(element *list.Element, bucket *list.List) {
+	entry := bucket.Remove(element).(*lfuEntry)
+	delete(c.access, entry.key)
+	if bucket.Len() == 0 {
+		delete(c.frequencies, entry.frequency)
+	}
+}
@@ -0,0 +1,133 @@
+//go:build !solution
+
+package lrucache
+
+import (
+	"container/list"
+)
+
+// TwoQueueCache implements the full 2Q policy. New entries go to a FIFO
+// (in), and only keys seen again after leaving it, remembered by a ghost
+// list (out), are promoted to the LRU main queue. A scan therefore only
+// flushes the FIFO.
+type TwoQueueCache struct {
+	capacity int
+	inSize   int
+	outSize  int
+	in       *list.List
+	main     *list.List
+	out      *list.List
+	inAccess map[int]*list.Element
+	access   map[int]*list.Element
+	// outAccess holds keys only, out elements store int
+	outAccess map[int]*list.Element
+}
+
+var _ Cache = (*TwoQueueCache)(nil)
+
+// NewTwoQueue sizes the FIFO at 25% and the ghost list at 50% of capacity,
+// as suggested by the 2Q paper.
+func NewTwoQueue(capacity int) Cache {
+	c := &TwoQueueCache{
+		capacity: capacity,
+		inSize:   atLeastOne(capacity / 4),
+		outSize:  atLeastOne(capacity / 2),
+	}
+	c.Clear()
+	return c
+}
+
+func (c *TwoQueueCache) Get(key int) (int, bool) {
+
+	// Hits in the main queue update the recency
+	if element, ok := c.access[key]; ok {
+		c.main.MoveToBack(element)
+		return element.Value.(Entry).value, true
+	}
+
+	// Hits in the FIFO do not count as reuse yet
+	if element, ok := c.inAccess[key]; ok {
+		return element.Value.(Entry).value, true
+	}
+
+	return 0, false
+}
+
+func (c *TwoQueueCache) Set(key, value int) {
+
+	// If capacity is not positive do nothing
+	if c.capacity <= 0 {
+		return
+	}
+
+	// If the key is present just update the value
+	if element, ok := c.access[key]; ok {
+		element.Value = Entry{key: key, value: value}
+		c.main.MoveToBack(element)
+		return
+	}
+	if element, ok := c.inAccess[key]; ok {
+		element.Value = Entry{key: key, value: value}
+		return
+	}
+
+	c.reclaim()
+
+	// A key evicted from the FIFO recently is hot, others start in the FIFO
+	if element, ok := c.outAccess[key]; ok {
+		c.out.Remove(element)
+		delete(c.outAccess, key)
+		c.access[key] = c.main.PushBack(Entry{key: key, value: value})
+		return
+	}
+	c.inAccess[key] = c.in.PushBack(Entry{key: key, value: value})
+}
+
+// Range visits the FIFO from the oldest entry, then the main queue from the
+// least recently used one.
+func (c *TwoQueueCache) Range(f func(key, value int) bool) {
+	for _, queue := range []*list.List{c.in, c.main} {
+		for element := queue.Front(); element != nil; element = element.Next() {
+			if !f(element.Value.(Entry).key, element.Value.(Entry).value) {
+				return
+			}
+		}
+	}
+}
+
+func (c *TwoQueueCache) Clear() {
+	c.in = list.New()
+	c.main = list.New()
+	c.out = list.New()
+	c.inAccess = map[int]*list.Element{}
+	c.access = map[int]*list.Element{}
+	c.outAccess = map[int]*list.Element{}
+}
+
+// reclaim frees a slot for a new entry when the cache is full.
+func (c *TwoQueueCache) reclaim() {
+	if c.in.Len()+c.main.Len() < c.capacity {
+		return
+	}
+
+	// Evict from the main queue unless the FIFO is over its share
+	if c.in.Len() < c.inSize && c.main.Len() > 0 {
+		delete(c.access, c.main.Remove(c.main.Front()).(Entry).key)
+		return
+	}
+
+	// Remember the key evicted from the FIFO in the ghost list
+	key := c.in.Remove(c.in.Front()).(Entry).key
+	delete(c.inAccess, key)
+	c.outAccess[key] = c.out.PushBack(key)
+	if c.out.Len() > c.outSize {
+		delete(c.outAccess, c.out.Remove(c.out.Front()).(int))
+	}
+}
+
+func atLeastOne(n int) int {
+	if n < 1 {
+		return 1
+	}
+	return n
+}
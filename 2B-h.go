@@ -0,0 +1,225 @@
+//go:build !solution
+
+package lrucache
+
+import (
+	"container/list"
+)
+
+const (
+	sketchDepth = 4
+	// Counters saturate like the 4-bit counters of the TinyLFU paper
+	sketchMaxCount = 15
+)
+
+// sketch is a count-min sketch estimating how often keys were seen. The
+// counters are halved every resetAfter increments, so old popularity fades.
+type sketch struct {
+	counters   [sketchDepth][]uint8
+	mask       uint64
+	additions  int
+	resetAfter int
+}
+
+func newSketch(capacity int) *sketch {
+	width := 16
+	for width < capacity {
+		width *= 2
+	}
+
+	s := &sketch{
+		mask:       uint64(width - 1),
+		resetAfter: 10 * width,
+	}
+	for i := range s.counters {
+		s.counters[i] = make([]uint8, width)
+	}
+	return s
+}
+
+var sketchSeeds = [sketchDepth]uint64{
+	0x9E3779B97F4A7C15, 0xC2B2AE3D27D4EB4F, 0x165667B19E3779F9, 0xD6E8FEB86659FD93,
+}
+
+func (s *sketch) index(row, key int) uint64 {
+	hash := (uint64(key) + sketchSeeds[row]) * sketchSeeds[(row+1)%sketchDepth]
+	return (hash >> 32) & s.mask
+}
+
+func (s *sketch) increment(key int) {
+	for row := range s.counters {
+		if i := s.index(row, key); s.counters[row][i] < sketchMaxCount {
+			s.counters[row][i]++
+		}
+	}
+
+	s.additions++
+	if s.additions == s.resetAfter {
+		for row := range s.counters {
+			for i := range s.counters[row] {
+				s.counters[row][i] /= 2
+			}
+		}
+		s.additions /= 2
+	}
+}
+
+func (s *sketch) estimate(key int) uint8 {
+	estimate := uint8(sketchMaxCount)
+	for row := range s.counters {
+		if count := s.counters[row][s.index(row, key)]; count < estimate {
+			estimate = count
+		}
+	}
+	return estimate
+}
+
+type tinyLFUSegment int
+
+const (
+	windowSegment tinyLFUSegment = iota
+	probationSegment
+	protectedSegment
+)
+
+type tinyLFUEntry struct {
+	key     int
+	value   int
+	segment tinyLFUSegment
+}
+
+// TinyLFUCache implements W-TinyLFU. New entries land in a small LRU window,
+// and an entry leaving the window only enters the main segmented LRU if the
+// frequency sketch rates it higher than the main eviction victim.
+type TinyLFUCache struct {
+	capacity      int
+	windowSize    int
+	protectedSize int
+	segments      [3]*list.List
+	access        map[int]*list.Element
+	sketch        *sketch
+}
+
+var _ Cache = (*TinyLFUCache)(nil)
+
+// NewTinyLFU gives 1% of capacity to the window and 80% of the rest to the
+// protected segment.
+func NewTinyLFU(capacity int) Cache {
+	windowSize := atLeastOne(capacity / 100)
+	c := &TinyLFUCache{
+		capacity:      capacity,
+		windowSize:    windowSize,
+		protectedSize: (capacity - windowSize) * 8 / 10,
+	}
+	c.Clear()
+	return c
+}
+
+func (c *TinyLFUCache) Get(key int) (int, bool) {
+	c.sketch.increment(key)
+
+	// Check for existence
+	element, ok := c.access[key]
+	if !ok {
+		return 0, false
+	}
+
+	c.touch(element)
+	return element.Value.(*tinyLFUEntry).value, true
+}
+
+func (c *TinyLFUCache) Set(key, value int) {
+
+	// If capacity is not positive do nothing
+	if c.capacity <= 0 {
+		return
+	}
+
+	// If the key is present just update the value
+	if element, ok := c.access[key]; ok {
+		element.Value.(*tinyLFUEntry).value = value
+		c.touch(element)
+		return
+	}
+
+	c.sketch.increment(key)
+	c.access[key] = c.segments[windowSegment].PushBack(&tinyLFUEntry{key: key, value: value})
+	if c.segments[windowSegment].Len() > c.windowSize {
+		c.admit(c.segments[windowSegment].Front())
+	}
+}
+
+// Range visits the window, then the probation and the protected segments,
+// each from the least recently used entry.
+func (c *TinyLFUCache) Range(f func(key, value int) bool) {
+	for _, segment := range c.segments {
+		for element := segment.Front(); element != nil; element = element.Next() {
+			entry := element.Value.(*tinyLFUEntry)
+			if !f(entry.key, entry.value) {
+				return
+			}
+		}
+	}
+}
+
+func (c *TinyLFUCache) Clear() {
+	for i := range c.segments {
+		c.segments[i] = list.New()
+	}
+	c.access = map[int]*list.Element{}
+	c.sketch = newSketch(c.capacity)
+}
+
+// touch updates the recency, a reused probation entry becomes protected.
+func (c *TinyLFUCache) touch(element *list.Element) {
+	entry := tinyEntry(element)
+	if entry.segment != probationSegment {
+		c.segments[entry.segment].MoveToBack(element)
+		return
+	}
+
+	c.move(element, protectedSegment)
+
+	// Demote the least recently used protected entry if it overflows
+	if protected := c.segments[protectedSegment]; protected.Len() > c.protectedSize {
+		c.move(protected.Front(), probationSegment)
+	}
+}
+
+// admit moves the entry leaving the window to the main segments if it is
+// more popular than the entry it would replace.
+func (c *TinyLFUCache) admit(candidate *list.Element) {
+	if len(c.access) <= c.capacity {
+		c.move(candidate, probationSegment)
+		return
+	}
+
+	victim := c.segments[probationSegment].Front()
+	if victim == nil {
+		victim = c.segments[protectedSegment].Front()
+	}
+
+	if victim != nil && c.sketch.estimate(tinyEntry(candidate).key) > c.sketch.estimate(tinyEntry(victim).key) {
+		c.remove(victim)
+		c.move(candidate, probationSegment)
+		return
+	}
+	c.remove(candidate)
+}
+
+func (c *TinyLFUCache) move(element *list.Element, segment tinyLFUSegment) {
+	entry := tinyEntry(element)
+	c.segments[entry.segment].Remove(element)
+	entry.segment = segment
+	c.access[entry.key] = c.segments[segment].PushBack(entry)
+}
+
+func (c *TinyLFUCache) remove(element *list.Element) {
+	entry := tinyEntry(element)
+	c.segments[entry.segment].Remove(element)
+	delete(c.access, entry.key)
+}
+
+func tinyEntry(element *list.Element) *tinyLFUEntry {
+	return element.Value.(*tinyLFUEntry)
+}
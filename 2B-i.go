@@ -0,0 +1,104 @@
+//go:build !solution
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"gitlab.com/slon/shad-go/lrucache"
+)
+
+var policies = []struct {
+	name string
+	new  func(capacity int) lrucache.Cache
+}{
+	{"LRU", func(capacity int) lrucache.Cache { return lrucache.New(capacity) }},
+	{"LFU", lrucache.NewLFU},
+	{"2Q", lrucache.NewTwoQueue},
+	{"W-TinyLFU", lrucache.NewTinyLFU},
+}
+
+// Replays a key trace against every eviction policy and reports the hit
+// ratio. The trace has one key per line, blank lines and lines starting
+// with # are skipped.
+func main() {
+	capacity := flag.Int("capacity", 1000, "cache capacity in entries")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [-capacity N] TRACE\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Read the trace
+	keys, err := readTrace(flag.Arg(0))
+	if err != nil {
+		log.Fatalf("Error reading trace: %v", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+	fmt.Fprintln(w, "Policy\tHits\tMisses\tHit ratio")
+	for _, policy := range policies {
+		hits := simulate(policy.new(*capacity), keys)
+		misses := len(keys) - hits
+
+		ratio := 0.0
+		if len(keys) > 0 {
+			ratio = float64(hits) / float64(len(keys))
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%.2f%%\n", policy.name, hits, misses, 100*ratio)
+	}
+	w.Flush()
+}
+
+// simulate loads every missed key into the cache and returns the number of
+// hits.
+func simulate(cache lrucache.Cache, keys []int) int {
+	hits := 0
+	for _, key := range keys {
+		if _, ok := cache.Get(key); ok {
+			hits++
+			continue
+		}
+		cache.Set(key, key)
+	}
+	return hits
+}
+
+// readTrace parses integer keys as is and hashes any other key.
+func readTrace(path string) ([]int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var keys []int
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if key, err := strconv.Atoi(line); err == nil {
+			keys = append(keys, key)
+			continue
+		}
+		hash := fnv.New64a()
+		hash.Write([]byte(line))
+		keys = append(keys, int(hash.Sum64()))
+	}
+	return keys, scanner.Err()
+}
@@ -0,0 +1,168 @@
+//go:build !solution
+
+package lrucache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Loader fetches the value of a missing key.
+type Loader func(ctx context.Context, key int) (int, error)
+
+type call struct {
+	done    chan struct{}
+	value   int
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+type failure struct {
+	err       error
+	expiresAt time.Time
+}
+
+// LoadingCache makes a cache safe for concurrent use and fills its misses
+// with a loader, running a single load per key however many callers miss it.
+type LoadingCache struct {
+	mutex sync.Mutex
+	cache Cache
+	calls map[int]*call
+	// failures remembers loader errors for failureTTL, nil disables it
+	failures   *GenericCache[int, failure]
+	failureTTL time.Duration
+}
+
+var _ Cache = (*LoadingCache)(nil)
+
+type LoadingOption func(*LoadingCache)
+
+// WithNegativeCache returns loader errors for up to capacity keys during ttl
+// instead of loading them again.
+func WithNegativeCache(capacity int, ttl time.Duration) LoadingOption {
+	return func(c *LoadingCache) {
+		c.failures = NewGeneric[int, failure](capacity)
+		c.failureTTL = ttl
+	}
+}
+
+func NewLoading(cache Cache, opts ...LoadingOption) *LoadingCache {
+	c := &LoadingCache{
+		cache: cache,
+		calls: map[int]*call{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// GetOrLoad returns the cached value or waits for the loader. Loader errors
+// are not cached unless the negative cache is enabled. A caller whose ctx is
+// done stops waiting, and the load is cancelled once no caller waits for it.
+func (c *LoadingCache) GetOrLoad(ctx context.Context, key int, loader Loader) (int, error) {
+	c.mutex.Lock()
+
+	// Check the cache and the recent failures
+	if value, ok := c.cache.Get(key); ok {
+		c.mutex.Unlock()
+		return value, nil
+	}
+	if c.failures != nil {
+		if f, ok := c.failures.Get(key); ok && time.Now().Before(f.expiresAt) {
+			c.mutex.Unlock()
+			return 0, f.err
+		}
+	}
+
+	// Join the running load or start a new one if there is none or it was
+	// abandoned, the load keeps the values of ctx but not its cancellation
+	cl, ok := c.calls[key]
+	if !ok || cl.waiters == 0 {
+		loadCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		cl = &call{done: make(chan struct{}), cancel: cancel}
+		c.calls[key] = cl
+		go c.load(loadCtx, key, loader, cl)
+	}
+	cl.waiters++
+	c.mutex.Unlock()
+
+	select {
+	case <-cl.done:
+		return cl.value, cl.err
+	case <-ctx.Done():
+		c.mutex.Lock()
+		cl.waiters--
+		if cl.waiters == 0 {
+			// Nobody needs the value anymore and later callers start
+			// afresh, but a value loaded meanwhile is still cached
+			cl.cancel()
+		}
+		c.mutex.Unlock()
+		return 0, ctx.Err()
+	}
+}
+
+func (c *LoadingCache) load(ctx context.Context, key int, loader Loader, cl *call) {
+	defer cl.cancel()
+	value, err := loader(ctx, key)
+
+	c.mutex.Lock()
+
+	// A load overtaken by Set or Clear only answers its waiters, the result
+	// may be older than what the cache holds now
+	if c.calls[key] == cl {
+		delete(c.calls, key)
+		if err == nil {
+			c.cache.Set(key, value)
+		} else if c.failures != nil && ctx.Err() == nil {
+			c.failures.Set(key, failure{err: err, expiresAt: time.Now().Add(c.failureTTL)})
+		}
+	}
+	cl.value, cl.err = value, err
+	c.mutex.Unlock()
+
+	close(cl.done)
+}
+
+func (c *LoadingCache) Get(key int) (int, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.cache.Get(key)
+}
+
+// Set overrides the result of a load of key in flight and a remembered
+// loader error.
+func (c *LoadingCache) Set(key, value int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.calls, key)
+	if c.failures != nil {
+		c.failures.removeKey(key)
+	}
+	c.cache.Set(key, value)
+}
+
+// Range holds the lock, so f must not use the cache.
+func (c *LoadingCache) Range(f func(key, value int) bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.cache.Range(f)
+}
+
+// Clear also drops the results of loads in flight.
+func (c *LoadingCache) Clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.calls = map[int]*call{}
+	c.cache.Clear()
+	if c.failures != nil {
+		c.failures.Clear()
+	}
+}
@@ -0,0 +1,332 @@
+//go:build !solution
+
+package lrucache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errLoad = errors.New("load failed")
+
+// waitCached polls c until key holds value, loads finish asynchronously.
+func waitCached(t *testing.T, c *LoadingCache, key, value int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if got, ok := c.Get(key); ok && got == value {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("key %d was not cached with value %d", key, value)
+}
+
+func TestLoadingCacheSingleFlight(t *testing.T) {
+	c := NewLoading(New(10))
+
+	var loads atomic.Int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context, key int) (int, error) {
+		loads.Add(1)
+		<-release
+		return key * 10, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if value, err := c.GetOrLoad(context.Background(), 1, loader); err != nil || value != 10 {
+				t.Errorf("GetOrLoad = %d, %v", value, err)
+			}
+		}()
+	}
+
+	// Let the callers join the load before it finishes
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := loads.Load(); n != 1 {
+		t.Fatalf("loader ran %d times", n)
+	}
+	if value, ok := c.Get(1); !ok || value != 10 {
+		t.Fatalf("Get(1) = %d, %v", value, ok)
+	}
+}
+
+func TestLoadingCacheErrorsAreNotCached(t *testing.T) {
+	c := NewLoading(New(10))
+
+	var loads atomic.Int32
+	loader := func(ctx context.Context, key int) (int, error) {
+		loads.Add(1)
+		return 0, errLoad
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetOrLoad(context.Background(), 1, loader); !errors.Is(err, errLoad) {
+			t.Fatalf("GetOrLoad error = %v", err)
+		}
+	}
+	if n := loads.Load(); n != 2 {
+		t.Fatalf("loader ran %d times", n)
+	}
+}
+
+func TestLoadingCacheNegativeCache(t *testing.T) {
+	c := NewLoading(New(10), WithNegativeCache(10, 50*time.Millisecond))
+
+	var loads atomic.Int32
+	loader := func(ctx context.Context, key int) (int, error) {
+		loads.Add(1)
+		return 0, errLoad
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.GetOrLoad(context.Background(), 1, loader); !errors.Is(err, errLoad) {
+			t.Fatalf("GetOrLoad error = %v", err)
+		}
+	}
+	if n := loads.Load(); n != 1 {
+		t.Fatalf("loader ran %d times during the ttl", n)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if _, err := c.GetOrLoad(context.Background(), 1, loader); !errors.Is(err, errLoad) {
+		t.Fatalf("GetOrLoad error = %v", err)
+	}
+	if n := loads.Load(); n != 2 {
+		t.Fatalf("loader ran %d times after the ttl", n)
+	}
+}
+
+func TestLoadingCacheSetForgetsFailure(t *testing.T) {
+	c := NewLoading(New(1), WithNegativeCache(10, time.Hour))
+
+	failing := func(ctx context.Context, key int) (int, error) { return 0, errLoad }
+	if _, err := c.GetOrLoad(context.Background(), 1, failing); !errors.Is(err, errLoad) {
+		t.Fatalf("GetOrLoad error = %v", err)
+	}
+
+	// Once the set value is evicted the key is loaded again instead of
+	// returning the old error
+	c.Set(1, 1)
+	c.Set(2, 2)
+	loader := func(ctx context.Context, key int) (int, error) { return key * 10, nil }
+	if value, err := c.GetOrLoad(context.Background(), 1, loader); err != nil || value != 10 {
+		t.Fatalf("GetOrLoad = %d, %v", value, err)
+	}
+}
+
+func TestLoadingCacheCancelledWaiter(t *testing.T) {
+	c := NewLoading(New(10))
+
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+	loader := func(ctx context.Context, key int) (int, error) {
+		close(started)
+		<-ctx.Done()
+		close(cancelled)
+		return 0, ctx.Err()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		_, err := c.GetOrLoad(ctx, 1, loader)
+		errs <- err
+	}()
+
+	<-started
+	cancel()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Fatalf("GetOrLoad error = %v", err)
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("load was not cancelled after the last waiter left")
+	}
+}
+
+func TestLoadingCacheLoadOutlivesOneWaiter(t *testing.T) {
+	c := NewLoading(New(10))
+
+	var loads atomic.Int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context, key int) (int, error) {
+		loads.Add(1)
+		select {
+		case <-release:
+			return key * 10, nil
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		_, err := c.GetOrLoad(ctx, 1, loader)
+		errs <- err
+	}()
+
+	results := make(chan int, 1)
+	go func() {
+		value, err := c.GetOrLoad(context.Background(), 1, loader)
+		if err != nil {
+			t.Errorf("GetOrLoad error = %v", err)
+		}
+		results <- value
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Fatalf("GetOrLoad error = %v", err)
+	}
+
+	close(release)
+	if value := <-results; value != 10 {
+		t.Fatalf("GetOrLoad = %d", value)
+	}
+	if n := loads.Load(); n != 1 {
+		t.Fatalf("loader ran %d times", n)
+	}
+}
+
+func TestLoadingCacheKeepsValueLoadedAfterWaitersLeft(t *testing.T) {
+	c := NewLoading(New(10))
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	loader := func(ctx context.Context, key int) (int, error) {
+		close(started)
+		// The loader ignores the cancellation and finishes anyway
+		<-release
+		return key * 10, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		_, err := c.GetOrLoad(ctx, 1, loader)
+		errs <- err
+	}()
+
+	<-started
+	cancel()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Fatalf("GetOrLoad error = %v", err)
+	}
+
+	close(release)
+	waitCached(t, c, 1, 10)
+}
+
+func TestLoadingCacheSetOverridesLoad(t *testing.T) {
+	c := NewLoading(New(10))
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	loader := func(ctx context.Context, key int) (int, error) {
+		close(started)
+		<-release
+		return 10, nil
+	}
+
+	results := make(chan int, 1)
+	go func() {
+		value, _ := c.GetOrLoad(context.Background(), 1, loader)
+		results <- value
+	}()
+
+	<-started
+	c.Set(1, 20)
+	close(release)
+
+	// The waiter gets the loaded value, the cache keeps the set one
+	if value := <-results; value != 10 {
+		t.Fatalf("GetOrLoad = %d", value)
+	}
+	if value, ok := c.Get(1); !ok || value != 20 {
+		t.Fatalf("Get(1) = %d, %v", value, ok)
+	}
+}
+
+func TestLoadingCacheClearDropsLoad(t *testing.T) {
+	c := NewLoading(New(10))
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	loader := func(ctx context.Context, key int) (int, error) {
+		close(started)
+		<-release
+		return 10, nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = c.GetOrLoad(context.Background(), 1, loader)
+	}()
+
+	<-started
+	c.Clear()
+	close(release)
+	<-done
+
+	if value, ok := c.Get(1); ok {
+		t.Fatalf("Get(1) = %d after Clear", value)
+	}
+}
+
+func TestLoadingCacheConcurrent(t *testing.T) {
+	c := NewLoading(New(32), WithNegativeCache(32, time.Millisecond))
+
+	loader := func(ctx context.Context, key int) (int, error) {
+		if key%7 == 0 {
+			return 0, errLoad
+		}
+		return key, nil
+	}
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+
+			for i := 0; i < 2000; i++ {
+				key := (worker*31 + i) % 64
+				switch i % 100 {
+				case 0:
+					c.Clear()
+				case 1:
+					c.Range(func(key, value int) bool { return true })
+				case 2:
+					c.Set(key, key)
+				default:
+					ctx, cancel := context.WithTimeout(context.Background(), time.Duration(i%3)*time.Microsecond)
+					value, err := c.GetOrLoad(ctx, key, loader)
+					cancel()
+					if err == nil && value != key {
+						t.Errorf("GetOrLoad(%d) = %d", key, value)
+						return
+					}
+				}
+			}
+		}(worker)
+	}
+	wg.Wait()
+}
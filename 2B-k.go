@@ -0,0 +1,82 @@
+//go:build !solution
+
+package lrucache
+
+import (
+	"sync/atomic"
+)
+
+// Stats describes how effective a cache is. Evictions count entries removed
+// for capacity or expiration, but not by Clear. Values rejected for their
+// cost count neither as insertions nor as evictions.
+type Stats struct {
+	Hits       uint64
+	Misses     uint64
+	Insertions uint64
+	Updates    uint64
+	Evictions  uint64
+}
+
+// HitRatio is the share of Get calls that found the key.
+func (s Stats) HitRatio() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// counters are atomic, so stats can be read while another goroutine owns
+// the cache.
+type counters struct {
+	hits       atomic.Uint64
+	misses     atomic.Uint64
+	insertions atomic.Uint64
+	updates    atomic.Uint64
+	evictions  atomic.Uint64
+}
+
+func (c *counters) snapshot() Stats {
+	return Stats{
+		Hits:       c.hits.Load(),
+		Misses:     c.misses.Load(),
+		Insertions: c.insertions.Load(),
+		Updates:    c.updates.Load(),
+		Evictions:  c.evictions.Load(),
+	}
+}
+
+func (c *counters) reset() {
+	c.hits.Store(0)
+	c.misses.Store(0)
+	c.insertions.Store(0)
+	c.updates.Store(0)
+	c.evictions.Store(0)
+}
+
+func (c *LRUCache) Stats() Stats {
+	return c.stats.snapshot()
+}
+
+func (c *LRUCache) ResetStats() {
+	c.stats.reset()
+}
+
+// Stats sums the stats of all segments.
+func (c *ShardedCache) Stats() Stats {
+	var total Stats
+	for _, s := range c.shards {
+		stats := s.cache.Stats()
+		total.Hits += stats.Hits
+		total.Misses += stats.Misses
+		total.Insertions += stats.Insertions
+		total.Updates += stats.Updates
+		total.Evictions += stats.Evictions
+	}
+	return total
+}
+
+func (c *ShardedCache) ResetStats() {
+	for _, s := range c.shards {
+		s.cache.ResetStats()
+	}
+}
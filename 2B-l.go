@@ -0,0 +1,133 @@
+//go:build !solution
+
+package lrucache
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Codec turns snapshot keys or values into bytes and back.
+type Codec interface {
+	Encode(v int) ([]byte, error)
+	Decode(data []byte) (int, error)
+}
+
+// VarintCodec is a compact binary codec.
+type VarintCodec struct{}
+
+func (VarintCodec) Encode(v int) ([]byte, error) {
+	return binary.AppendVarint(nil, int64(v)), nil
+}
+
+func (VarintCodec) Decode(data []byte) (int, error) {
+	v, n := binary.Varint(data)
+	if n != len(data) {
+		return 0, fmt.Errorf("invalid varint %x", data)
+	}
+	return int(v), nil
+}
+
+// DecimalCodec writes numbers as text, which keeps snapshots readable.
+type DecimalCodec struct{}
+
+func (DecimalCodec) Encode(v int) ([]byte, error) {
+	return strconv.AppendInt(nil, int64(v), 10), nil
+}
+
+func (DecimalCodec) Decode(data []byte) (int, error) {
+	return strconv.Atoi(string(data))
+}
+
+var snapshotMagic = []byte("LRUSNAP1")
+
+// Dump writes the entries in Range order, which for LRUCache is from the
+// least to the most recently used. Each key and value is a big-endian uint32
+// length followed by the encoded bytes. TTLs and costs are not saved.
+func Dump(w io.Writer, c Cache, keys, values Codec) error {
+	writer := bufio.NewWriter(w)
+	if _, err := writer.Write(snapshotMagic); err != nil {
+		return err
+	}
+
+	var err error
+	c.Range(func(key, value int) bool {
+		if err = writeRecord(writer, keys, key); err != nil {
+			return false
+		}
+		err = writeRecord(writer, values, value)
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return writer.Flush()
+}
+
+// Load sets the entries of a snapshot in order, so a cache of the same kind
+// ends up with the dumped recency order. Entries already in c are kept, and
+// a failed load leaves the entries read so far.
+func Load(r io.Reader, c Cache, keys, values Codec) error {
+	reader := bufio.NewReader(r)
+
+	magic := make([]byte, len(snapshotMagic))
+	if _, err := io.ReadFull(reader, magic); err != nil || string(magic) != string(snapshotMagic) {
+		return errors.New("not a cache snapshot")
+	}
+
+	for {
+		key, err := readRecord(reader, keys)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		value, err := readRecord(reader, values)
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		} else if err != nil {
+			return err
+		}
+
+		c.Set(key, value)
+	}
+}
+
+func writeRecord(w io.Writer, codec Codec, v int) error {
+	data, err := codec.Encode(v)
+	if err != nil {
+		return err
+	}
+
+	record := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(record, uint32(len(data)))
+	copy(record[4:], data)
+	_, err = w.Write(record)
+	return err
+}
+
+// readRecord returns io.EOF only if r ends before the record.
+func readRecord(r io.Reader, codec Codec) (int, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, err
+	}
+
+	// A corrupted length must not allocate more than the input holds
+	var data bytes.Buffer
+	if _, err := io.CopyN(&data, r, int64(binary.BigEndian.Uint32(header))); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+
+	return codec.Decode(data.Bytes())
+}
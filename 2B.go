@@ -4,25 +4,43 @@ package lrucache
 
 import (
 	"container/list"
+	"time"
 )
 
 type Entry struct {
 	key   int
 	value int
+	// expiresAt is zero for entries without a TTL
+	expiresAt time.Time
+	cost      int64
 }
 
 type LRUCache struct {
 	capacity int
 	cache    *list.List
 	access   map[int]*list.Element
+	ttl      time.Duration
+	now      func() time.Time
+	onEvict  func(key, value int, reason EvictionReason)
+	// With a cost function maxCost bounds totalCost instead of capacity
+	// bounding the number of entries
+	cost      func(key, value int) int64
+	maxCost   int64
+	totalCost int64
+	stats     counters
 }
 
-func New(capacity int) Cache {
-	return &LRUCache{
+func New(capacity int, opts ...Option) Cache {
+	c := &LRUCache{
 		capacity: capacity,
 		cache:    list.New(),
 		access:   map[int]*list.Element{},
+		now:      time.Now,
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 func (c *LRUCache) Get(key int) (int, bool) {
@@ -30,39 +48,79 @@ func (c *LRUCache) Get(key int) (int, bool) {
 	// Check for existence
 	element, ok := c.access[key]
 	if !ok {
+		c.stats.misses.Add(1)
+		return 0, false
+	}
+
+	// Expired entries are removed lazily
+	if c.expired(element.Value.(Entry)) {
+		c.removeElement(element, EvictedExpired)
+		c.stats.misses.Add(1)
 		return 0, false
 	}
 
 	// Update the access time
+	c.stats.hits.Add(1)
 	c.cache.MoveToBack(element)
 	return element.Value.(Entry).value, true
 }
 
 func (c *LRUCache) Set(key, value int) {
+	c.SetWithTTL(key, value, c.ttl)
+}
+
+// SetWithTTL stores the entry for ttl, or forever if ttl is not positive.
+func (c *LRUCache) SetWithTTL(key, value int, ttl time.Duration) {
 
 	// If capacity is 0 do nothing
-	if c.capacity == 0 {
+	if c.cost == nil && c.capacity == 0 {
 		return
 	}
 
-	// If the key is present just update the value
+	entry := Entry{key: key, value: value}
+	if ttl > 0 {
+		entry.expiresAt = c.now().Add(ttl)
+	}
+	if c.cost != nil {
+		entry.cost = c.cost(key, value)
+
+		// An entry costlier than the whole cache is rejected instead of
+		// flushing everything else, the old value of the key is evicted
+		if entry.cost > c.maxCost {
+			if element, ok := c.access[key]; ok {
+				c.removeElement(element, EvictedCapacity)
+			}
+			if c.onEvict != nil {
+				c.onEvict(key, value, EvictedRejected)
+			}
+			return
+		}
+	}
+
 	if element, ok := c.access[key]; ok {
-		element.Value = Entry{key, value}
+		// If the key is present just update the value
+		c.stats.updates.Add(1)
+		c.totalCost += entry.cost - element.Value.(Entry).cost
+		element.Value = entry
 		c.cache.MoveToBack(element)
-		return
+	} else {
+		// If the key is missing add the entry to the data
+		c.stats.insertions.Add(1)
+		c.access[key] = c.cache.PushBack(entry)
+		c.totalCost += entry.cost
 	}
 
-	// If the key is missing add the entry to the data and check the capacity
-	element := c.cache.PushBack(Entry{key, value})
-	c.access[key] = element
-	if c.capacity < c.cache.Len() {
-		delete(c.access, c.cache.Front().Value.(Entry).key)
-		c.cache.Remove(c.cache.Front())
+	// Drop the least recently used entries until the cache fits
+	for c.cache.Len() > 0 && c.overCapacity() {
+		c.removeElement(c.cache.Front(), EvictedCapacity)
 	}
 }
 
 func (c *LRUCache) Range(f func(key, value int) bool) {
 	for element := c.cache.Front(); element != nil; element = element.Next() {
+		if c.expired(element.Value.(Entry)) {
+			continue
+		}
 		if !f(element.Value.(Entry).key, element.Value.(Entry).value) {
 			return
 		}
@@ -70,6 +128,13 @@ func (c *LRUCache) Range(f func(key, value int) bool) {
 }
 
 func (c *LRUCache) Clear() {
+	if c.onEvict != nil {
+		for element := c.cache.Front(); element != nil; element = element.Next() {
+			entry := element.Value.(Entry)
+			c.onEvict(entry.key, entry.value, EvictedCleared)
+		}
+	}
+	c.totalCost = 0
 	c.cache.Init()
 	c.access = map[int]*list.Element{}
 }
@@ -9,7 +9,7 @@ import (
 )
 
 type CommandLineArgs struct {
-	RepositoryPath       *string
+	RepositoryPaths      *[]string
 	CommitPointer        *string
 	SortOrderKey         string
 	UseCommitter         *bool
@@ -18,6 +18,13 @@ type CommandLineArgs struct {
 	ProgrammingLanguages *[]string
 	ExcludePatterns      *[]string
 	IncludePatterns      *[]string
+	ExcludeGenerated     *bool
+	LanguagesFile        *string
+	DiffRevisions        []string
+	BusFactorReport      bool
+	RecurseSubmodules    *bool
+	ShowRepository       *bool
+	CoAuthors            *string
 }
 
 func NewCommandLineArgs() *CommandLineArgs {
@@ -25,7 +32,7 @@ func NewCommandLineArgs() *CommandLineArgs {
 }
 
 func (cla *CommandLineArgs) GetCommandLineArgs() error {
-	cla.RepositoryPath = flag.String("repository", "./", "Path to the repository.")
+	cla.RepositoryPaths = flag.StringSlice("repository", []string{"./"}, "Paths to the repositories.")
 	cla.CommitPointer = flag.String("revision", "HEAD", "Reference to a specific commit.")
 	sortOrder := flag.String("order-by", "lines", "Key for sorting.")
 	cla.UseCommitter = flag.Bool("use-committer", false, "Enable use of committer information.")
@@ -34,21 +41,51 @@ func (cla *CommandLineArgs) GetCommandLineArgs() error {
 	cla.ProgrammingLanguages = flag.StringSlice("languages", []string{}, "Programming languages to search for.")
 	cla.ExcludePatterns = flag.StringSlice("exclude", []string{}, "Glob patterns to exclude.")
 	cla.IncludePatterns = flag.StringSlice("restrict-to", []string{}, "Patterns to include in the search.")
+	cla.LanguagesFile = flag.String("languages-file", "", "Path to a JSON file overriding the default language mapping.")
+	cla.RecurseSubmodules = flag.Bool("recurse-submodules", false, "Include files of submodules at their pinned commits.")
+	cla.ShowRepository = flag.Bool("show-repository", false, "Split statistics by repository and print a repository column.")
+	cla.CoAuthors = flag.String("co-authors", "none", "Credit Co-authored-by trailers: 'none', 'full' or 'split' (rounded shares).")
+	cla.ExcludeGenerated = flag.Bool("exclude-generated", false, "Exclude files marked linguist-generated or linguist-vendored in .gitattributes.")
 
 	flag.Parse()
 
-	// Validate repository path
-	if _, err := os.Stat(*cla.RepositoryPath); os.IsNotExist(err) {
-		return fmt.Errorf("repository path missing: %s", *cla.RepositoryPath)
+	// Validate repository paths
+	for _, repositoryPath := range *cla.RepositoryPaths {
+		if _, err := os.Stat(repositoryPath); os.IsNotExist(err) {
+			return fmt.Errorf("repository path missing: %s", repositoryPath)
+		}
 	}
 
-	// Validate commit pointer by executing git show command
-	gitShowCmd := exec.Command("git", "show", *cla.CommitPointer)
-	gitShowCmd.Dir = *cla.RepositoryPath
+	// Handle commands. "diff REV1 REV2" replaces --revision
+	revisions := []string{*cla.CommitPointer}
+	switch flag.Arg(0) {
+	case "":
+	case "diff":
+		if flag.NArg() != 3 {
+			return fmt.Errorf("usage: gitfame diff REV1 REV2")
+		}
+		cla.DiffRevisions = flag.Args()[1:]
+		revisions = cla.DiffRevisions
+	case "bus-factor":
+		if flag.NArg() != 1 {
+			return fmt.Errorf("usage: gitfame bus-factor")
+		}
+		cla.BusFactorReport = true
+	default:
+		return fmt.Errorf("unknown command: %s. Permitted commands: 'diff', 'bus-factor'", flag.Arg(0))
+	}
+
+	// Validate commit pointers by executing git show command
+	for _, repositoryPath := range *cla.RepositoryPaths {
+		for _, revision := range revisions {
+			gitShowCmd := exec.Command("git", "show", revision)
+			gitShowCmd.Dir = repositoryPath
 
-	err := gitShowCmd.Run()
-	if err != nil {
-		return fmt.Errorf("commit missing: %s", *cla.CommitPointer)
+			err := gitShowCmd.Run()
+			if err != nil {
+				return fmt.Errorf("commit missing: %s in %s", revision, repositoryPath)
+			}
+		}
 	}
 
 	// Validate sort order key
@@ -59,6 +96,13 @@ func (cla *CommandLineArgs) GetCommandLineArgs() error {
 		return fmt.Errorf("key error: %s. Permitted keys: 'lines', 'commits', 'files'", *sortOrder)
 	}
 
+	// Validate co-authors mode
+	switch *cla.CoAuthors {
+	case "none", "full", "split":
+	default:
+		return fmt.Errorf("co-authors mode error: %s. Permitted modes: 'none', 'full', 'split'", *cla.CoAuthors)
+	}
+
 	// Validate output format
 	switch *outputFormat {
 	case "tabular", "csv", "json", "json-lines":
@@ -8,15 +8,22 @@ import (
 )
 
 type MappingEntity struct {
-	Name       string
-	Type       string
-	Extensions []string
+	Name         string
+	Type         string
+	Extensions   []string
+	Filenames    []string
+	Interpreters []string
 }
 
 type FilesParams struct {
-	FilesList []string
-	Cla       *CommandLineArgs
-	Mapping   []MappingEntity
+	FilesList     []string
+	Cla           *CommandLineArgs
+	Mapping       []MappingEntity
+	GitDir        string
+	CommitPointer string
+	Repository    string
+	Prefix        string
+	Submodules    []*FilesParams
 }
 
 func NewFilesParams(mapping []MappingEntity, cla *CommandLineArgs) *FilesParams {
@@ -24,8 +31,11 @@ func NewFilesParams(mapping []MappingEntity, cla *CommandLineArgs) *FilesParams
 }
 
 func (fp *FilesParams) GetAllFiles(commitPointer, gitDir string) {
+	fp.CommitPointer = commitPointer
+	fp.GitDir = gitDir
+
 	// Execute git ls-tree command
-	gitLsTreeCmd := exec.Command("git", "ls-tree", "-r", "--name-only", commitPointer)
+	gitLsTreeCmd := exec.Command("git", "ls-tree", "-r", commitPointer)
 	gitLsTreeCmd.Dir = gitDir
 
 	var gitLsTreeCmdOutput strings.Builder
@@ -36,12 +46,31 @@ func (fp *FilesParams) GetAllFiles(commitPointer, gitDir string) {
 		log.Fatalf("Get all files %v", err)
 	}
 
+	// Separate files from submodule commits
 	gitTree := gitLsTreeCmdOutput.String()
-	filesInfo := strings.Split(gitTree, "\n")
-	for _, file := range filesInfo {
-		if file == "" {
+	var filesInfo []string
+	var submodules [][2]string
+	for _, line := range strings.Split(gitTree, "\n") {
+		meta, file, found := strings.Cut(line, "\t")
+		if !found {
+			continue
+		}
+
+		fields := strings.Fields(meta)
+		if len(fields) == 3 && fields[1] == "commit" {
+			submodules = append(submodules, [2]string{file, fields[2]})
 			continue
 		}
+		filesInfo = append(filesInfo, file)
+	}
+
+	// Read .gitattributes only when they are needed
+	var attrs *gitAttributes
+	if *fp.Cla.ExcludeGenerated {
+		attrs = loadGitAttributes(commitPointer, gitDir, filesInfo)
+	}
+
+	for _, file := range filesInfo {
 
 		// Check file extension
 		if len(*fp.Cla.FileExtensions) > 0 {
@@ -60,17 +89,8 @@ func (fp *FilesParams) GetAllFiles(commitPointer, gitDir string) {
 
 		// Determine file language
 		fileLanguage := ""
-		fileExtension := filepath.Ext(file)
-		for _, mappingEntity := range fp.Mapping {
-			for _, extension := range mappingEntity.Extensions {
-				if strings.EqualFold(fileExtension, extension) {
-					fileLanguage = mappingEntity.Name
-					break
-				}
-			}
-			if fileLanguage != "" {
-				break
-			}
+		if len(*fp.Cla.ProgrammingLanguages) > 0 {
+			fileLanguage = fp.detectLanguage(file, commitPointer, gitDir)
 		}
 
 		// Check if language is acceptable
@@ -91,7 +111,7 @@ func (fp *FilesParams) GetAllFiles(commitPointer, gitDir string) {
 		if len(*fp.Cla.ExcludePatterns) > 0 {
 			excludeMatch := false
 			for _, pattern := range *fp.Cla.ExcludePatterns {
-				match, _ := filepath.Match(pattern, file)
+				match := matchGlob(pattern, fp.Prefix+file)
 				if match {
 					excludeMatch = true
 					break
@@ -106,7 +126,7 @@ func (fp *FilesParams) GetAllFiles(commitPointer, gitDir string) {
 		if len(*fp.Cla.IncludePatterns) > 0 {
 			includeMatch := false
 			for _, pattern := range *fp.Cla.IncludePatterns {
-				match, _ := filepath.Match(pattern, file)
+				match := matchGlob(pattern, fp.Prefix+file)
 				if match {
 					includeMatch = true
 					break
@@ -117,6 +137,49 @@ func (fp *FilesParams) GetAllFiles(commitPointer, gitDir string) {
 			}
 		}
 
+		// Check generated and vendored files
+		if attrs != nil && attrs.isGeneratedOrVendored(file) {
+			continue
+		}
+
 		fp.FilesList = append(fp.FilesList, file)
 	}
+
+	if *fp.Cla.RecurseSubmodules {
+		for _, submodule := range submodules {
+			fp.addSubmodule(submodule[0], submodule[1])
+		}
+	}
+}
+
+// addSubmodule collects files of the submodule at path at its pinned commit.
+func (fp *FilesParams) addSubmodule(path, commit string) {
+	submoduleDir := filepath.Join(fp.GitDir, path)
+
+	// The submodule must be initialized to read its history
+	gitCatFileCmd := exec.Command("git", "cat-file", "-e", commit+"^{commit}")
+	gitCatFileCmd.Dir = submoduleDir
+	if err := gitCatFileCmd.Run(); err != nil {
+		log.Printf("Skipping uninitialized submodule %s", fp.Prefix+path)
+		return
+	}
+
+	submodule := &FilesParams{
+		Cla:        fp.Cla,
+		Mapping:    fp.Mapping,
+		Repository: fp.Repository,
+		Prefix:     fp.Prefix + path + "/",
+	}
+	submodule.GetAllFiles(commit, submoduleDir)
+
+	fp.Submodules = append(fp.Submodules, submodule)
+}
+
+// statPath is the name of file in statistics. Files of different
+// repositories are told apart by the repository name.
+func (fp *FilesParams) statPath(file string) string {
+	if len(*fp.Cla.RepositoryPaths) > 1 {
+		return fp.Repository + "/" + fp.Prefix + file
+	}
+	return fp.Prefix + file
 }
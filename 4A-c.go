@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"text/tabwriter"
 )
 
@@ -14,13 +15,13 @@ func (stats *stats) Print(format string) {
 	switch format {
 	case "tabular":
 		w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
-		_, err := fmt.Fprintln(w, "Name\tLines\tCommits\tFiles")
+		_, err := fmt.Fprintln(w, strings.Join(stats.header(), "\t"))
 		if err != nil {
 			log.Fatalf("tabular: %v", err)
 		}
 
 		for _, line := range stats.sortedData {
-			_, err = fmt.Fprintln(w, line[0]+"\t"+line[1]+"\t"+line[2]+"\t"+line[3])
+			_, err = fmt.Fprintln(w, strings.Join(stats.row(line), "\t"))
 			if err != nil {
 				log.Fatalf("tabular: %v", err)
 			}
@@ -32,12 +33,11 @@ func (stats *stats) Print(format string) {
 		}
 
 	case "csv":
-		header := []string{"Name", "Lines", "Commits", "Files"}
 		w := csv.NewWriter(os.Stdout)
 		var buff [][]string
-		buff = append(buff, header)
+		buff = append(buff, stats.header())
 		for _, line := range stats.sortedData {
-			buff = append(buff, []string{line[0], line[1], line[2], line[3]})
+			buff = append(buff, stats.row(line))
 		}
 		err := w.WriteAll(buff)
 		if err != nil {
@@ -62,12 +62,7 @@ func (stats *stats) Print(format string) {
 				log.Fatalf("json: could not convert num of files: %v", err)
 			}
 
-			buff = append(buff, map[string]interface{}{
-				"name":    line[0],
-				"lines":   lines,
-				"commits": commits,
-				"files":   files,
-			})
+			buff = append(buff, stats.jsonRow(line[0], lines, commits, files))
 		}
 		jsonData, err := json.Marshal(buff)
 		if err != nil {
@@ -93,12 +88,7 @@ func (stats *stats) Print(format string) {
 				log.Fatalf("json-lines: could not convert num of files: %v", err)
 			}
 
-			jsonLine, err := json.Marshal(map[string]interface{}{
-				"name":    line[0],
-				"lines":   lines,
-				"commits": commits,
-				"files":   files,
-			})
+			jsonLine, err := json.Marshal(stats.jsonRow(line[0], lines, commits, files))
 			if err != nil {
 				log.Fatalf("json-lines: could not marshal json: %v", err)
 			}
@@ -110,3 +100,34 @@ func (stats *stats) Print(format string) {
 		log.Fatalf("Print: unsupported format %s", format)
 	}
 }
+
+func (stats *stats) header() []string {
+	header := []string{"Name", "Lines", "Commits", "Files"}
+	if stats.showRepository {
+		header = append(header, "Repository")
+	}
+	return header
+}
+
+func (stats *stats) row(line [4]string) []string {
+	name, repository := splitAuthor(line[0])
+	row := []string{name, line[1], line[2], line[3]}
+	if stats.showRepository {
+		row = append(row, repository)
+	}
+	return row
+}
+
+func (stats *stats) jsonRow(user string, lines, commits, files int) map[string]interface{} {
+	name, repository := splitAuthor(user)
+	row := map[string]interface{}{
+		"name":    name,
+		"lines":   lines,
+		"commits": commits,
+		"files":   files,
+	}
+	if stats.showRepository {
+		row["repository"] = repository
+	}
+	return row
+}
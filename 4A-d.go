@@ -2,6 +2,7 @@ package internal
 
 import (
 	"log"
+	"math"
 	"os/exec"
 	"sort"
 	"strconv"
@@ -9,60 +10,114 @@ import (
 )
 
 type stats struct {
-	userToLines      map[string]int
+	userToLines      map[string]float64
 	userToCommits    map[string]map[string]bool
-	userToNumCommits map[string]int
-	userToFiles      map[string]map[string]bool
-	userToNumFiles   map[string]int
+	userToNumCommits map[string]float64
+	userToFiles      map[string]map[string]float64
+	userToNumFiles   map[string]float64
 	combinedData     map[string][3]int
+	fileToUserLines  map[string]map[string]float64
 	sortedData       [][4]string
+	isDiff           bool
+	showRepository   bool
+}
+
+// Separates the repository from the author name when statistics are split
+// by repository
+const repositorySeparator = "\x00"
+
+func withRepository(author, repository string) string {
+	if repository == "" {
+		return author
+	}
+	return author + repositorySeparator + repository
+}
+
+func splitAuthor(user string) (string, string) {
+	name, repository, _ := strings.Cut(user, repositorySeparator)
+	return name, repository
+}
+
+func authorName(user string) string {
+	name, _ := splitAuthor(user)
+	return name
 }
 
 var totalStats stats
 
-func CountStatistics(fp *FilesParams) stats {
+func CountStatistics(filesParams ...*FilesParams) stats {
 	totalStats = stats{
-		userToLines:      make(map[string]int),
+		userToLines:      make(map[string]float64),
 		userToCommits:    make(map[string]map[string]bool),
-		userToNumCommits: make(map[string]int),
-		userToFiles:      make(map[string]map[string]bool),
-		userToNumFiles:   make(map[string]int),
+		userToNumCommits: make(map[string]float64),
+		userToFiles:      make(map[string]map[string]float64),
+		userToNumFiles:   make(map[string]float64),
 		combinedData:     make(map[string][3]int),
+		fileToUserLines:  make(map[string]map[string]float64),
 	}
 
-	for _, path := range fp.FilesList {
-		processFile(path, *fp.Cla.RepositoryPath, *fp.Cla.CommitPointer, *fp.Cla.UseCommitter)
+	for _, fp := range filesParams {
+		totalStats.showRepository = *fp.Cla.ShowRepository
+		countFiles(fp)
 	}
 
 	totalStats.combineResults()
 	return totalStats
 }
 
-func addLine(author string) {
-	totalStats.userToLines[author]++
+func countFiles(fp *FilesParams) {
+	for _, path := range fp.FilesList {
+		processFile(fp, path)
+	}
+
+	for _, submodule := range fp.Submodules {
+		countFiles(submodule)
+	}
+}
+
+func addLine(author, path string, share float64) {
+	totalStats.userToLines[author] += share
+
+	if _, ok := totalStats.fileToUserLines[path]; !ok {
+		totalStats.fileToUserLines[path] = make(map[string]float64)
+	}
+	totalStats.fileToUserLines[path][author] += share
 }
 
-func addCommit(author, commit string) {
+func addCommit(author, commit string, share float64) {
 	if _, ok := totalStats.userToCommits[author]; !ok {
 		totalStats.userToCommits[author] = make(map[string]bool)
 	}
 	if _, ok := totalStats.userToCommits[author][commit]; !ok {
 		totalStats.userToCommits[author][commit] = true
-		totalStats.userToNumCommits[author]++
+		totalStats.userToNumCommits[author] += share
 	}
 }
 
-func addFile(author, path string) {
+// addFile credits the file with the largest share the author has in it.
+func addFile(author, path string, share float64) {
 	if _, ok := totalStats.userToFiles[author]; !ok {
-		totalStats.userToFiles[author] = make(map[string]bool)
+		totalStats.userToFiles[author] = make(map[string]float64)
 	}
-	if _, ok := totalStats.userToFiles[author][path]; !ok {
-		totalStats.userToFiles[author][path] = true
-		totalStats.userToNumFiles[author]++
+	if previousShare, ok := totalStats.userToFiles[author][path]; !ok || previousShare < share {
+		totalStats.userToFiles[author][path] = share
+		totalStats.userToNumFiles[author] += share - previousShare
 	}
 }
 
-func processFile(path, gitDir, commitPointer string, useCommiter bool) {
+func addCredits(credits []credit, commitHash, path string, withLine bool) {
+	for _, c := range credits {
+		if withLine {
+			addLine(c.author, path, c.share)
+		}
+		addCommit(c.author, commitHash, c.share)
+		addFile(c.author, path, c.share)
+	}
+}
+
+func processFile(fp *FilesParams, path string) {
+	gitDir, commitPointer, statPath := fp.GitDir, fp.CommitPointer, fp.statPath(path)
+
 	// Execute git blame command
 	gitBlameCmd := exec.Command("git", "blame", "--line-porcelain", "-b", commitPointer, path)
 	gitBlameCmd.Dir = gitDir
@@ -97,14 +152,13 @@ func processFile(path, gitDir, commitPointer string, useCommiter bool) {
 		words := strings.Split(logLines[1], " ")
 		author = strings.Join(words[1:len(words)-1], " ")
 
-		addCommit(author, commitHash)
-		addFile(author, path)
+		addCredits(fp.creditedAuthors(author, commitHash), commitHash, statPath, false)
 	}
 
 	for i := 0; i < len(statLines); i++ {
 		words := strings.Split(statLines[i], " ")
 
-		if useCommiter {
+		if *fp.Cla.UseCommitter {
 			if words[0] == "committer" {
 				commitHash = strings.Split(statLines[i-5], " ")[0]
 				author = strings.Join(words[1:], " ")
@@ -120,74 +174,113 @@ func processFile(path, gitDir, commitPointer string, useCommiter bool) {
 			}
 		}
 
-		addLine(author)
-		addCommit(author, commitHash)
-		addFile(author, path)
+		addCredits(fp.creditedAuthors(author, commitHash), commitHash, statPath, true)
 	}
 }
 
 func (stats *stats) combineResults() {
-	for name, numCommits := range stats.userToNumCommits {
-		numLines := 0
+	// Fractional credits of co-authors are rounded so that every column
+	// still adds up to its total
+	lines := apportion(stats.userToLines)
+	commits := apportion(stats.userToNumCommits)
+	files := apportion(stats.userToNumFiles)
 
-		if actualNumLines, ok := stats.userToLines[name]; ok {
-			numLines = actualNumLines
-		}
+	for name := range stats.userToNumCommits {
+		stats.combinedData[name] = [3]int{lines[name], commits[name], files[name]}
+	}
+}
 
-		stats.combinedData[name] = [3]int{
-			numLines,
-			numCommits,
-			stats.userToNumFiles[name],
+// Shares of split commits are sums of fractions such as 1/3, so values
+// closer than this are considered equal
+const shareEpsilon = 1e-9
+
+// apportion rounds the values by the largest remainder method: everybody gets
+// the integer part, and the units left to reach the rounded total go to the
+// largest fractional parts, equal ones by name.
+func apportion(values map[string]float64) map[string]int {
+	rounded := make(map[string]int, len(values))
+	fractions := make(map[string]float64, len(values))
+	users := make([]string, 0, len(values))
+	total, floorTotal := 0.0, 0
+	for user, value := range values {
+		floor := math.Floor(value + shareEpsilon)
+		rounded[user] = int(floor)
+		fractions[user] = math.Max(value-floor, 0)
+		users = append(users, user)
+		total += value
+		floorTotal += rounded[user]
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		if difference := fractions[users[i]] - fractions[users[j]]; math.Abs(difference) > shareEpsilon {
+			return difference > 0
 		}
+		return users[i] < users[j]
+	})
+
+	left := int(math.Round(total)) - floorTotal
+	for i := 0; i < left && i < len(users); i++ {
+		rounded[users[i]]++
 	}
+
+	return rounded
 }
 
 func (stats *stats) SortResults(sortKey string) {
 	var users []string
 	for user := range stats.userToNumCommits {
-		if user != "Not Committed Yet" {
+		if authorName(user) != "Not Committed Yet" {
 			users = append(users, user)
 		}
 	}
 
+	// Changes between revisions are ordered by their absolute value
+	sortData := stats.combinedData
+	if stats.isDiff {
+		sortData = make(map[string][3]int, len(stats.combinedData))
+		for user, data := range stats.combinedData {
+			sortData[user] = [3]int{abs(data[0]), abs(data[1]), abs(data[2])}
+		}
+	}
+
 	if sortKey == "lines" {
 		sort.SliceStable(users, func(i, j int) bool {
-			if stats.combinedData[users[i]][0] == stats.combinedData[users[j]][0] {
-				if stats.combinedData[users[i]][1] == stats.combinedData[users[j]][1] {
-					if stats.combinedData[users[i]][2] == stats.combinedData[users[j]][2] {
+			if sortData[users[i]][0] == sortData[users[j]][0] {
+				if sortData[users[i]][1] == sortData[users[j]][1] {
+					if sortData[users[i]][2] == sortData[users[j]][2] {
 						return users[i] < users[j]
 					}
-					return stats.combinedData[users[i]][2] > stats.combinedData[users[j]][2]
+					return sortData[users[i]][2] > sortData[users[j]][2]
 				}
-				return stats.combinedData[users[i]][1] > stats.combinedData[users[j]][1]
+				return sortData[users[i]][1] > sortData[users[j]][1]
 			}
-			return stats.combinedData[users[i]][0] > stats.combinedData[users[j]][0]
+			return sortData[users[i]][0] > sortData[users[j]][0]
 		})
 	} else if sortKey == "commits" {
 		sort.SliceStable(users, func(i, j int) bool {
-			if stats.combinedData[users[i]][1] == stats.combinedData[users[j]][1] {
-				if stats.combinedData[users[i]][0] == stats.combinedData[users[j]][0] {
-					if stats.combinedData[users[i]][2] == stats.combinedData[users[j]][2] {
+			if sortData[users[i]][1] == sortData[users[j]][1] {
+				if sortData[users[i]][0] == sortData[users[j]][0] {
+					if sortData[users[i]][2] == sortData[users[j]][2] {
 						return users[i] < users[j]
 					}
-					return stats.combinedData[users[i]][2] > stats.combinedData[users[j]][2]
+					return sortData[users[i]][2] > sortData[users[j]][2]
 				}
-				return stats.combinedData[users[i]][0] > stats.combinedData[users[j]][0]
+				return sortData[users[i]][0] > sortData[users[j]][0]
 			}
-			return stats.combinedData[users[i]][1] > stats.combinedData[users[j]][1]
+			return sortData[users[i]][1] > sortData[users[j]][1]
 		})
 	} else if sortKey == "files" {
 		sort.SliceStable(users, func(i, j int) bool {
-			if stats.combinedData[users[i]][2] == stats.combinedData[users[j]][2] {
-				if stats.combinedData[users[i]][0] == stats.combinedData[users[j]][0] {
-					if stats.combinedData[users[i]][1] == stats.combinedData[users[j]][1] {
+			if sortData[users[i]][2] == sortData[users[j]][2] {
+				if sortData[users[i]][0] == sortData[users[j]][0] {
+					if sortData[users[i]][1] == sortData[users[j]][1] {
 						return users[i] < users[j]
 					}
-					return stats.combinedData[users[i]][1] > stats.combinedData[users[j]][1]
+					return sortData[users[i]][1] > sortData[users[j]][1]
 				}
-				return stats.combinedData[users[i]][0] > stats.combinedData[users[j]][0]
+				return sortData[users[i]][0] > sortData[users[j]][0]
 			}
-			return stats.combinedData[users[i]][2] > stats.combinedData[users[j]][2]
+			return sortData[users[i]][2] > sortData[users[j]][2]
 		})
 	}
 
@@ -0,0 +1,48 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApportion(t *testing.T) {
+	third := 1.0 / 3
+
+	for _, test := range []struct {
+		name   string
+		values map[string]float64
+		want   map[string]int
+	}{
+		{
+			name:   "whole",
+			values: map[string]float64{"Alice": 3, "Bob": 1},
+			want:   map[string]int{"Alice": 3, "Bob": 1},
+		},
+		{
+			name:   "halves go by name",
+			values: map[string]float64{"Carol": 0.5, "Bob": 0.5},
+			want:   map[string]int{"Bob": 1, "Carol": 0},
+		},
+		{
+			name:   "thirds go by name despite rounding noise",
+			values: map[string]float64{"Alice": 6 + third, "Bob": third, "Carol": third},
+			want:   map[string]int{"Alice": 7, "Bob": 0, "Carol": 0},
+		},
+		{
+			name:   "largest remainder first",
+			values: map[string]float64{"Alice": 1.1, "Bob": 0.6, "Carol": 1.3},
+			want:   map[string]int{"Alice": 1, "Bob": 1, "Carol": 1},
+		},
+		{
+			name:   "sums of thirds stay whole",
+			values: map[string]float64{"Alice": third + third + third, "Bob": 2},
+			want:   map[string]int{"Alice": 1, "Bob": 2},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := apportion(test.values); !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("apportion(%v) = %v, want %v", test.values, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package internal
+
+import (
+	"log"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+// matchGlob reports whether file matches pattern. Besides the usual
+// path.Match syntax, "**" matches any number of path segments and a pattern
+// that matches a leading directory of file matches everything below it.
+func matchGlob(pattern, file string) bool {
+	pattern = strings.TrimPrefix(pattern, "./")
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "**"
+	}
+
+	patternParts := strings.Split(pattern, "/")
+	fileParts := strings.Split(file, "/")
+
+	// Try the whole path first, then each of its leading directories
+	for n := len(fileParts); n > 0; n-- {
+		if matchSegments(patternParts, fileParts[:n]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchWholePath reports whether pattern matches all of file, which is how
+// .gitattributes patterns match: a pattern matching a directory does not
+// match the files below it, "dir/**" does.
+func matchWholePath(pattern, file string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(file, "/"))
+}
+
+func matchSegments(patternParts, fileParts []string) bool {
+	if len(patternParts) == 0 {
+		return len(fileParts) == 0
+	}
+
+	if patternParts[0] == "**" {
+		// Collapse consecutive "**" and let the rest match any suffix
+		for i := 0; i <= len(fileParts); i++ {
+			if matchSegments(patternParts[1:], fileParts[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(fileParts) == 0 {
+		return false
+	}
+
+	match, err := path.Match(patternParts[0], fileParts[0])
+	if err != nil || !match {
+		return false
+	}
+
+	return matchSegments(patternParts[1:], fileParts[1:])
+}
+
+type attributeRule struct {
+	dir       string
+	pattern   string
+	generated *bool
+	vendored  *bool
+}
+
+type gitAttributes struct {
+	rules []attributeRule
+}
+
+// loadGitAttributes reads every .gitattributes file of the revision. The
+// files are expected in ls-tree order, so deeper files come after their
+// parents and override them.
+func loadGitAttributes(commitPointer, gitDir string, files []string) *gitAttributes {
+	attrs := &gitAttributes{}
+
+	for _, file := range files {
+		if path.Base(file) != ".gitattributes" {
+			continue
+		}
+
+		gitShowCmd := exec.Command("git", "show", commitPointer+":"+file)
+		gitShowCmd.Dir = gitDir
+
+		var gitShowCmdOutput strings.Builder
+		gitShowCmd.Stdout = &gitShowCmdOutput
+
+		err := gitShowCmd.Run()
+		if err != nil {
+			log.Fatalf("Load gitattributes %s: %v", file, err)
+		}
+
+		dir := path.Dir(file)
+		if dir == "." {
+			dir = ""
+		}
+
+		for _, line := range strings.Split(gitShowCmdOutput.String(), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+				continue
+			}
+
+			rule := attributeRule{dir: dir, pattern: fields[0]}
+			for _, attr := range fields[1:] {
+				switch name, value := parseAttribute(attr); name {
+				case "linguist-generated":
+					rule.generated = &value
+				case "linguist-vendored":
+					rule.vendored = &value
+				}
+			}
+
+			if rule.generated != nil || rule.vendored != nil {
+				attrs.rules = append(attrs.rules, rule)
+			}
+		}
+	}
+
+	return attrs
+}
+
+// parseAttribute turns "attr", "-attr", "!attr" and "attr=value" into a name
+// and whether the attribute is set.
+func parseAttribute(attr string) (string, bool) {
+	switch {
+	case strings.HasPrefix(attr, "-"), strings.HasPrefix(attr, "!"):
+		return attr[1:], false
+	case strings.Contains(attr, "="):
+		name, value, _ := strings.Cut(attr, "=")
+		return name, value != "false"
+	default:
+		return attr, true
+	}
+}
+
+func (rule *attributeRule) matches(file string) bool {
+	if rule.dir != "" {
+		if !strings.HasPrefix(file, rule.dir+"/") {
+			return false
+		}
+		file = strings.TrimPrefix(file, rule.dir+"/")
+	}
+
+	// A pattern without a slash matches the file name at any depth
+	pattern := strings.TrimPrefix(rule.pattern, "/")
+	if !strings.Contains(rule.pattern, "/") {
+		pattern = "**/" + pattern
+	}
+
+	return matchWholePath(pattern, file)
+}
+
+// isGeneratedOrVendored reports whether the last matching rule marks file as
+// linguist-generated or linguist-vendored.
+func (attrs *gitAttributes) isGeneratedOrVendored(file string) bool {
+	generated, vendored := false, false
+
+	for i := range attrs.rules {
+		rule := &attrs.rules[i]
+		if !rule.matches(file) {
+			continue
+		}
+		if rule.generated != nil {
+			generated = *rule.generated
+		}
+		if rule.vendored != nil {
+			vendored = *rule.vendored
+		}
+	}
+
+	return generated || vendored
+}
@@ -0,0 +1,105 @@
+package internal
+
+import (
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	for _, test := range []struct {
+		pattern string
+		file    string
+		want    bool
+	}{
+		{pattern: "*.go", file: "main.go", want: true},
+		{pattern: "*.go", file: "cmd/main.go", want: false},
+		{pattern: "./*.go", file: "main.go", want: true},
+		{pattern: "cmd/*.go", file: "cmd/main.go", want: true},
+		{pattern: "**/*.go", file: "main.go", want: true},
+		{pattern: "**/*.go", file: "a/b/main.go", want: true},
+		{pattern: "a/**/*.go", file: "a/b/c/main.go", want: true},
+		{pattern: "a/**/*.go", file: "b/main.go", want: false},
+		{pattern: "vendor", file: "vendor/lib/lib.go", want: true},
+		{pattern: "vendor/", file: "vendor/lib.go", want: true},
+		{pattern: "vendor/", file: "vendored.go", want: false},
+		{pattern: "[", file: "main.go", want: false},
+	} {
+		if got := matchGlob(test.pattern, test.file); got != test.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", test.pattern, test.file, got, test.want)
+		}
+	}
+}
+
+func TestParseAttribute(t *testing.T) {
+	for _, test := range []struct {
+		attr      string
+		wantName  string
+		wantValue bool
+	}{
+		{attr: "linguist-generated", wantName: "linguist-generated", wantValue: true},
+		{attr: "-linguist-generated", wantName: "linguist-generated", wantValue: false},
+		{attr: "!linguist-vendored", wantName: "linguist-vendored", wantValue: false},
+		{attr: "linguist-vendored=true", wantName: "linguist-vendored", wantValue: true},
+		{attr: "linguist-vendored=false", wantName: "linguist-vendored", wantValue: false},
+		{attr: "text=auto", wantName: "text", wantValue: true},
+	} {
+		name, value := parseAttribute(test.attr)
+		if name != test.wantName || value != test.wantValue {
+			t.Errorf("parseAttribute(%q) = %q, %v, want %q, %v", test.attr, name, value, test.wantName, test.wantValue)
+		}
+	}
+}
+
+func TestAttributeRuleMatches(t *testing.T) {
+	for _, test := range []struct {
+		dir     string
+		pattern string
+		file    string
+		want    bool
+	}{
+		{pattern: "*.pb.go", file: "api/api.pb.go", want: true},
+		{pattern: "*.pb.go", file: "api.pb.go", want: true},
+		// Unlike matchGlob, a directory name does not match its contents
+		{pattern: "vendor", file: "vendor/lib/lib.go", want: false},
+		{pattern: "vendor", file: "a/vendor", want: true},
+		{pattern: "vendor/", file: "vendor/lib.go", want: false},
+		{pattern: "vendor/**", file: "vendor/lib/lib.go", want: true},
+		{pattern: "/gen/*.go", file: "gen/a.go", want: true},
+		{pattern: "/gen/*.go", file: "x/gen/a.go", want: false},
+		{pattern: "gen/*.go", file: "x/gen/a.go", want: false},
+		{dir: "x", pattern: "gen/*.go", file: "x/gen/a.go", want: true},
+		{dir: "x", pattern: "*.go", file: "y/a.go", want: false},
+		{dir: "x", pattern: "*.go", file: "x/y/a.go", want: true},
+	} {
+		rule := attributeRule{dir: test.dir, pattern: test.pattern}
+		if got := rule.matches(test.file); got != test.want {
+			t.Errorf("rule %q in %q matches %q = %v, want %v", test.pattern, test.dir, test.file, got, test.want)
+		}
+	}
+}
+
+func TestGitAttributesPrecedence(t *testing.T) {
+	set, unset := true, false
+	attrs := &gitAttributes{rules: []attributeRule{
+		{pattern: "*.go", generated: &set},
+		{pattern: "main.go", generated: &unset},
+		{pattern: "vendor/**", vendored: &set},
+		{dir: "vendor/own", pattern: "*", vendored: &unset},
+	}}
+
+	for _, test := range []struct {
+		file string
+		want bool
+	}{
+		{file: "lib.go", want: true},
+		{file: "cmd/main.go", want: false},
+		{file: "README.md", want: false},
+		{file: "vendor/lib/README.md", want: true},
+		{file: "vendor/own/README.md", want: false},
+		// The later rule only unsets vendored, generated still holds
+		{file: "vendor/own/lib.go", want: true},
+	} {
+		if got := attrs.isGeneratedOrVendored(test.file); got != test.want {
+			t.Errorf("isGeneratedOrVendored(%q) = %v, want %v", test.file, got, test.want)
+		}
+	}
+}
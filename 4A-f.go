@@ -0,0 +1,11 @@
+package configs
+
+import (
+	_ "embed"
+)
+
+// LanguageExtensions is the default language mapping bundled into the binary,
+// so gitfame works regardless of the directory it is started from.
+//
+//go:embed language_extensions.json
+var LanguageExtensions []byte
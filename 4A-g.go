@@ -0,0 +1,125 @@
+package internal
+
+import (
+	"log"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Languages of well-known files that have no meaningful extension
+var languageByFilename = map[string]string{
+	"Makefile":       "Makefile",
+	"makefile":       "Makefile",
+	"GNUmakefile":    "Makefile",
+	"Dockerfile":     "Dockerfile",
+	"Containerfile":  "Dockerfile",
+	"CMakeLists.txt": "CMake",
+	"Rakefile":       "Ruby",
+	"Gemfile":        "Ruby",
+	"Jenkinsfile":    "Groovy",
+	"Vagrantfile":    "Ruby",
+}
+
+// Languages of interpreters found in "#!" lines
+var languageByInterpreter = map[string]string{
+	"sh":      "Shell",
+	"bash":    "Shell",
+	"zsh":     "Shell",
+	"ksh":     "Shell",
+	"dash":    "Shell",
+	"python":  "Python",
+	"perl":    "Perl",
+	"ruby":    "Ruby",
+	"node":    "JavaScript",
+	"php":     "PHP",
+	"lua":     "Lua",
+	"Rscript": "R",
+	"tclsh":   "Tcl",
+	"awk":     "Awk",
+}
+
+// detectLanguage determines the language of file by its name, then by its
+// extension and, for files without an extension, by its shebang line.
+func (fp *FilesParams) detectLanguage(file, commitPointer, gitDir string) string {
+	baseName := filepath.Base(file)
+
+	// Check file name
+	for _, mappingEntity := range fp.Mapping {
+		for _, filename := range mappingEntity.Filenames {
+			if baseName == filename {
+				return mappingEntity.Name
+			}
+		}
+	}
+	if language, ok := languageByFilename[baseName]; ok {
+		return language
+	}
+
+	// Check file extension
+	fileExtension := filepath.Ext(file)
+	if fileExtension != "" {
+		for _, mappingEntity := range fp.Mapping {
+			for _, extension := range mappingEntity.Extensions {
+				if strings.EqualFold(fileExtension, extension) {
+					return mappingEntity.Name
+				}
+			}
+		}
+		return ""
+	}
+
+	// Check shebang
+	interpreter := readInterpreter(file, commitPointer, gitDir)
+	if interpreter == "" {
+		return ""
+	}
+	for _, mappingEntity := range fp.Mapping {
+		for _, name := range mappingEntity.Interpreters {
+			if interpreter == name {
+				return mappingEntity.Name
+			}
+		}
+	}
+
+	return languageByInterpreter[interpreter]
+}
+
+// readInterpreter returns the interpreter named in the "#!" line of file with
+// the version suffix stripped, e.g. "python" for "#!/usr/bin/env python3.11".
+func readInterpreter(file, commitPointer, gitDir string) string {
+	gitCatFileCmd := exec.Command("git", "cat-file", "-p", commitPointer+":"+file)
+	gitCatFileCmd.Dir = gitDir
+
+	var gitCatFileCmdOutput strings.Builder
+	gitCatFileCmd.Stdout = &gitCatFileCmdOutput
+
+	err := gitCatFileCmd.Run()
+	if err != nil {
+		log.Fatalf("Read shebang %s: %v", file, err)
+	}
+
+	firstLine, _, _ := strings.Cut(gitCatFileCmdOutput.String(), "\n")
+	if !strings.HasPrefix(firstLine, "#!") {
+		return ""
+	}
+
+	words := strings.Fields(strings.TrimPrefix(firstLine, "#!"))
+	if len(words) == 0 {
+		return ""
+	}
+
+	// Skip env and its options
+	interpreter := filepath.Base(words[0])
+	if interpreter == "env" {
+		interpreter = ""
+		for _, word := range words[1:] {
+			if !strings.HasPrefix(word, "-") && !strings.Contains(word, "=") {
+				interpreter = filepath.Base(word)
+				break
+			}
+		}
+	}
+
+	return strings.TrimRight(interpreter, "0123456789.")
+}
@@ -0,0 +1,116 @@
+package internal
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// CountStatisticsAt collects the files of revision in every repository and
+// counts their combined statistics. The revision becomes the current commit
+// pointer of cla.
+func CountStatisticsAt(mapping []MappingEntity, cla *CommandLineArgs, revision string) stats {
+	*cla.CommitPointer = revision
+
+	names := repositoryNames(*cla.RepositoryPaths)
+
+	var filesParams []*FilesParams
+	for i, repositoryPath := range *cla.RepositoryPaths {
+		fp := NewFilesParams(mapping, cla)
+		fp.Repository = names[i]
+		fp.GetAllFiles(*fp.Cla.CommitPointer, repositoryPath)
+
+		filesParams = append(filesParams, fp)
+	}
+
+	return CountStatistics(filesParams...)
+}
+
+// repositoryNames names every repository by the shortest trailing part of its
+// absolute path that no other repository shares, e.g. "app" or "a/app" and
+// "b/app". A repository given twice gets its position appended.
+func repositoryNames(repositoryPaths []string) []string {
+	components := make([][]string, len(repositoryPaths))
+	for i, repositoryPath := range repositoryPaths {
+		absPath, err := filepath.Abs(repositoryPath)
+		if err != nil {
+			absPath = filepath.Clean(repositoryPath)
+		}
+		components[i] = strings.Split(filepath.ToSlash(absPath), "/")
+	}
+
+	suffix := func(i, depth int) string {
+		if depth > len(components[i]) {
+			depth = len(components[i])
+		}
+		return strings.Join(components[i][len(components[i])-depth:], "/")
+	}
+
+	names := make([]string, len(repositoryPaths))
+	for i := range repositoryPaths {
+		for depth := 1; ; depth++ {
+			names[i] = suffix(i, depth)
+
+			unique := true
+			for j := range repositoryPaths {
+				if j != i && suffix(j, depth) == names[i] {
+					unique = false
+					break
+				}
+			}
+
+			if unique {
+				break
+			}
+			if depth >= len(components[i]) {
+				names[i] += "#" + strconv.Itoa(i+1)
+				break
+			}
+		}
+	}
+
+	return names
+}
+
+// DiffStatistics returns per author changes in lines, commits and files
+// between two snapshots. Authors whose numbers did not change are omitted.
+func DiffStatistics(before, after stats) stats {
+	diff := stats{
+		userToNumCommits: make(map[string]float64),
+		combinedData:     make(map[string][3]int),
+		isDiff:           true,
+		showRepository:   after.showRepository,
+	}
+
+	for name, afterData := range after.combinedData {
+		beforeData := before.combinedData[name]
+		diff.combinedData[name] = [3]int{
+			afterData[0] - beforeData[0],
+			afterData[1] - beforeData[1],
+			afterData[2] - beforeData[2],
+		}
+	}
+
+	for name, beforeData := range before.combinedData {
+		if _, ok := after.combinedData[name]; !ok {
+			diff.combinedData[name] = [3]int{-beforeData[0], -beforeData[1], -beforeData[2]}
+		}
+	}
+
+	for name, data := range diff.combinedData {
+		if data == [3]int{} {
+			delete(diff.combinedData, name)
+			continue
+		}
+		diff.userToNumCommits[name] = float64(data[1])
+	}
+
+	return diff
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,206 @@
+package internal
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+type ownership struct {
+	path      string
+	kind      string
+	lines     int
+	owner     string
+	share     float64
+	authors50 int
+	authors80 int
+	risk      string
+}
+
+// BusFactor computes line ownership concentration for every file and every
+// directory containing them. The riskiest areas come first.
+func (stats *stats) BusFactor() []ownership {
+	dirToUserLines := make(map[string]map[string]float64)
+	var report []ownership
+
+	for file, userLines := range stats.fileToUserLines {
+		report = append(report, newOwnership(file, "file", userLines))
+
+		// Add the lines to every enclosing directory up to the root
+		for dir := path.Dir(file); ; dir = path.Dir(dir) {
+			if _, ok := dirToUserLines[dir]; !ok {
+				dirToUserLines[dir] = make(map[string]float64)
+			}
+			for user, lines := range userLines {
+				dirToUserLines[dir][user] += lines
+			}
+			if dir == "." {
+				break
+			}
+		}
+	}
+
+	for dir, userLines := range dirToUserLines {
+		report = append(report, newOwnership(dir, "dir", userLines))
+	}
+
+	sort.SliceStable(report, func(i, j int) bool {
+		if report[i].share == report[j].share {
+			if report[i].lines == report[j].lines {
+				return report[i].path < report[j].path
+			}
+			return report[i].lines > report[j].lines
+		}
+		return report[i].share > report[j].share
+	})
+
+	return report
+}
+
+func newOwnership(path, kind string, statsUserLines map[string]float64) ownership {
+	// Authors are counted once regardless of the repository
+	userLines := make(map[string]float64)
+	for user, lines := range statsUserLines {
+		userLines[authorName(user)] += lines
+	}
+
+	var users []string
+	total := 0.0
+	for user, lines := range userLines {
+		if user == "Not Committed Yet" {
+			continue
+		}
+		users = append(users, user)
+		total += lines
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		if userLines[users[i]] == userLines[users[j]] {
+			return users[i] < users[j]
+		}
+		return userLines[users[i]] > userLines[users[j]]
+	})
+
+	result := ownership{path: path, kind: kind, lines: int(math.Round(total)), risk: "low"}
+	if total == 0 {
+		return result
+	}
+
+	result.owner = users[0]
+	result.share = userLines[users[0]] / total
+
+	// Find the minimum number of authors owning 50% and 80% of lines
+	owned := 0.0
+	for i, user := range users {
+		owned += userLines[user]
+		if result.authors50 == 0 && 2*owned >= total {
+			result.authors50 = i + 1
+		}
+		if 5*owned >= 4*total {
+			result.authors80 = i + 1
+			break
+		}
+	}
+
+	// A single author owning most of the code is a risk
+	if result.authors80 == 1 {
+		result.risk = "high"
+	} else if result.authors50 == 1 {
+		result.risk = "medium"
+	}
+
+	return result
+}
+
+func PrintBusFactor(report []ownership, format string) {
+	header := []string{"Path", "Type", "Lines", "Owner", "Share", "Authors50", "Authors80", "Risk"}
+
+	switch format {
+	case "tabular":
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+		_, err := fmt.Fprintln(w, strings.Join(header, "\t"))
+		if err != nil {
+			log.Fatalf("tabular: %v", err)
+		}
+
+		for _, row := range report {
+			_, err = fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%.2f\t%d\t%d\t%s\n",
+				row.path, row.kind, row.lines, row.owner, row.share, row.authors50, row.authors80, row.risk)
+			if err != nil {
+				log.Fatalf("tabular: %v", err)
+			}
+		}
+
+		err = w.Flush()
+		if err != nil {
+			log.Fatalf("tabular: %v", err)
+		}
+
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		var buff [][]string
+		buff = append(buff, header)
+		for _, row := range report {
+			buff = append(buff, []string{
+				row.path,
+				row.kind,
+				strconv.Itoa(row.lines),
+				row.owner,
+				strconv.FormatFloat(row.share, 'f', 2, 64),
+				strconv.Itoa(row.authors50),
+				strconv.Itoa(row.authors80),
+				row.risk,
+			})
+		}
+		err := w.WriteAll(buff)
+		if err != nil {
+			log.Fatalf("csv: %v", err)
+		}
+
+	case "json":
+		var buff []map[string]interface{}
+		for _, row := range report {
+			buff = append(buff, row.toMap())
+		}
+		jsonData, err := json.Marshal(buff)
+		if err != nil {
+			log.Fatalf("json: could not marshal json: %v", err)
+		}
+
+		fmt.Println(string(jsonData))
+
+	case "json-lines":
+		for _, row := range report {
+			jsonLine, err := json.Marshal(row.toMap())
+			if err != nil {
+				log.Fatalf("json-lines: could not marshal json: %v", err)
+			}
+
+			fmt.Println(string(jsonLine))
+		}
+
+	default:
+		log.Fatalf("PrintBusFactor: unsupported format %s", format)
+	}
+}
+
+func (row *ownership) toMap() map[string]interface{} {
+	return map[string]interface{}{
+		"path":       row.path,
+		"type":       row.kind,
+		"lines":      row.lines,
+		"owner":      row.owner,
+		"share":      row.share,
+		"authors_50": row.authors50,
+		"authors_80": row.authors80,
+		"risk":       row.risk,
+	}
+}
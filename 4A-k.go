@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"log"
+	"os/exec"
+	"strings"
+)
+
+type credit struct {
+	author string
+	share  float64
+}
+
+// Co-authors of already seen commits by repository and commit hash
+var coAuthorsCache = make(map[string][]string)
+
+// creditedAuthors returns who is credited for a line of commitHash. Without
+// co-authors mode only the author is credited, otherwise every co-author from
+// the commit trailers is credited either fully or with an equal share.
+func (fp *FilesParams) creditedAuthors(author, commitHash string) []credit {
+	repository := ""
+	if *fp.Cla.ShowRepository {
+		repository = fp.Repository
+	}
+
+	authors := []string{author}
+	if *fp.Cla.CoAuthors != "none" {
+		for _, coAuthor := range readCoAuthors(fp.GitDir, commitHash) {
+			if coAuthor != author {
+				authors = append(authors, coAuthor)
+			}
+		}
+	}
+
+	share := 1.0
+	if *fp.Cla.CoAuthors == "split" {
+		share /= float64(len(authors))
+	}
+
+	credits := make([]credit, 0, len(authors))
+	for _, name := range authors {
+		credits = append(credits, credit{author: withRepository(name, repository), share: share})
+	}
+
+	return credits
+}
+
+// readCoAuthors returns the names from the Co-authored-by trailers of commit.
+func readCoAuthors(gitDir, commitHash string) []string {
+	// Uncommitted lines have no commit message
+	if strings.Trim(commitHash, "0") == "" {
+		return nil
+	}
+
+	cacheKey := gitDir + ":" + commitHash
+	if coAuthors, ok := coAuthorsCache[cacheKey]; ok {
+		return coAuthors
+	}
+
+	gitLogCmd := exec.Command("git", "log", "-1", "--format=%(trailers:key=Co-authored-by,valueonly,unfold)", commitHash)
+	gitLogCmd.Dir = gitDir
+
+	var gitLogCmdOutput strings.Builder
+	gitLogCmd.Stdout = &gitLogCmdOutput
+
+	err := gitLogCmd.Run()
+	if err != nil {
+		log.Fatalf("Read co-authors: %v", err)
+	}
+
+	var coAuthors []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(gitLogCmdOutput.String(), "\n") {
+		// Drop the e-mail, blame reports names only
+		name := strings.TrimSpace(line)
+		if idx := strings.LastIndex(name, "<"); idx >= 0 {
+			name = strings.TrimSpace(name[:idx])
+		}
+
+		if name != "" && !seen[name] {
+			seen[name] = true
+			coAuthors = append(coAuthors, name)
+		}
+	}
+
+	coAuthorsCache[cacheKey] = coAuthors
+	return coAuthors
+}
@@ -4,8 +4,8 @@ import (
 	"encoding/json"
 	"log"
 	"os"
-	"path/filepath"
 
+	"gitlab.com/slon/shad-go/gitfame/configs"
 	"gitlab.com/slon/shad-go/gitfame/internal"
 )
 
@@ -17,45 +17,41 @@ func main() {
 		log.Fatalf("Failed to get command line arguments:\n%v", err1)
 	}
 
-	// Load language mapping
-	currentDir, err2 := os.Getwd()
-	if err2 != nil {
-		log.Fatalf("Failed to get current working directory:\n%v", err2)
-	}
-
-	// Find the root directory containing the go.mod file
-	rootMarker := "go.mod"
-	rootPath := currentDir
-	for {
-		if _, err3 := os.Stat(filepath.Join(rootPath, rootMarker)); err3 == nil {
-			break
+	// Load language mapping, falling back to the embedded default
+	mappingData := configs.LanguageExtensions
+	if *args.LanguagesFile != "" {
+		var err2 error
+		mappingData, err2 = os.ReadFile(*args.LanguagesFile)
+		if err2 != nil {
+			log.Fatalf("Failed to read language mapping file:\n%v", err2)
 		}
-		rootPath = filepath.Dir(rootPath)
 	}
 
-	mappingData, err4 := os.Open(filepath.Join(rootPath, "gitfame/configs/language_extensions.json"))
-	if err4 != nil {
-		log.Fatalf("Failed to open language extensions JSON file:\n%v", err4)
+	var languageMapping []internal.MappingEntity
+	err3 := json.Unmarshal(mappingData, &languageMapping)
+	if err3 != nil {
+		log.Fatalf("Failed to decode language mapping JSON:\n%v", err3)
 	}
-	defer func(mappingData *os.File) {
-		err5 := mappingData.Close()
-		if err5 != nil {
-			log.Fatalf("Failed to close language extensions JSON file:\n%v", err5)
-		}
-	}(mappingData)
 
-	var languageMapping []internal.MappingEntity
-	err6 := json.NewDecoder(mappingData).Decode(&languageMapping)
-	if err6 != nil {
-		log.Fatalf("Failed to decode language mapping JSON:\n%v", err6)
+	// Compare two revisions if requested
+	if args.DiffRevisions != nil {
+		before := internal.CountStatisticsAt(languageMapping, args, args.DiffRevisions[0])
+		after := internal.CountStatisticsAt(languageMapping, args, args.DiffRevisions[1])
+
+		diff := internal.DiffStatistics(before, after)
+		diff.SortResults(args.SortOrderKey)
+		diff.Print(args.OutputFormat)
+		return
 	}
 
-	// Initialize file parameters
-	filesParams := internal.NewFilesParams(languageMapping, args)
-	filesParams.GetAllFiles(*filesParams.Cla.CommitPointer, *filesParams.Cla.RepositoryPath)
+	// Collect files and count statistics
+	stats := internal.CountStatisticsAt(languageMapping, args, *args.CommitPointer)
 
-	// Count statistics
-	stats := internal.CountStatistics(filesParams)
+	// Report ownership concentration instead of per author totals
+	if args.BusFactorReport {
+		internal.PrintBusFactor(stats.BusFactor(), args.OutputFormat)
+		return
+	}
 
 	// Sort and print results based on command line arguments
 	stats.SortResults(args.SortOrderKey)
@@ -0,0 +1,56 @@
+//go:build !solution
+
+package pubsub
+
+import (
+	"errors"
+	"strings"
+)
+
+// validateSubject checks a NATS-style subject. Tokens are separated by dots,
+// "*" matches exactly one token and ">" matches the rest of the subject.
+func validateSubject(subject string, allowWildcards bool) error {
+	if subject == "" {
+		return errors.New("empty subject")
+	}
+
+	tokens := strings.Split(subject, ".")
+	for i, token := range tokens {
+		switch {
+		case token == "":
+			return errors.New("subject has an empty token")
+		case token == "*" || token == ">":
+			if !allowWildcards {
+				return errors.New("wildcards are not allowed in published subjects")
+			}
+			if token == ">" && i != len(tokens)-1 {
+				return errors.New("'>' must be the last token of a subject")
+			}
+		case strings.ContainsAny(token, "*>"):
+			return errors.New("wildcards must be whole tokens")
+		}
+	}
+
+	return nil
+}
+
+// matchSubject reports whether a published subject matches a subscription
+// pattern.
+func matchSubject(pattern, subject string) bool {
+	patternTokens := strings.Split(pattern, ".")
+	subjectTokens := strings.Split(subject, ".")
+
+	for i, token := range patternTokens {
+		if token == ">" {
+			return len(subjectTokens) > i
+		}
+		if i >= len(subjectTokens) {
+			return false
+		}
+		if token != "*" && token != subjectTokens[i] {
+			return false
+		}
+	}
+
+	return len(patternTokens) == len(subjectTokens)
+}
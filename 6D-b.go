@@ -0,0 +1,168 @@
+//go:build !solution
+
+package pubsub
+
+import (
+	"time"
+)
+
+// RetentionPolicy bounds the messages a topic keeps. Messages consumed by all
+// current subscribers are always dropped; the limits below additionally drop
+// the oldest messages even if slow subscribers have not seen them yet.
+// Zero values mean no limit. Messages older than MaxAge are dropped on the
+// next publish or delivery, and by a sweep every MaxAge/2 for idle topics.
+type RetentionPolicy struct {
+	MaxMessages int
+	MaxBytes    int
+	MaxAge      time.Duration
+
+	// Size reports the size of a message for MaxBytes. By default only
+	// strings and byte slices have a size.
+	Size func(msg interface{}) int
+}
+
+type Option func(*PubSubSystem)
+
+// WithRetention sets the retention policy of every topic.
+func WithRetention(policy RetentionPolicy) Option {
+	return func(ps *PubSubSystem) {
+		ps.retention = policy
+	}
+}
+
+// WithTopicRetention sets the retention policy of the topic with the given
+// subscription subject, overriding WithRetention.
+func WithTopicRetention(subject string, policy RetentionPolicy) Option {
+	return func(ps *PubSubSystem) {
+		ps.topicRetention[subject] = policy
+	}
+}
+
+type storedMessage struct {
+	message *Message
+	size    int
+}
+
+func defaultMessageSize(msg interface{}) int {
+	switch m := msg.(type) {
+	case string:
+		return len(m)
+	case []byte:
+		return len(m)
+	default:
+		return 0
+	}
+}
+
+func (topic *PubSubSystem) newStoredMessage(message *Message) storedMessage {
+	stored := storedMessage{message: message}
+
+	if topic.retention.MaxBytes > 0 {
+		if topic.retention.Size != nil {
+			stored.size = topic.retention.Size(message.Data)
+		} else {
+			stored.size = defaultMessageSize(message.Data)
+		}
+	}
+
+	return stored
+}
+
+// shortestMaxAge returns the smallest MaxAge of the retention policies, or
+// zero if none has one.
+func (ps *PubSubSystem) shortestMaxAge() time.Duration {
+	shortest := ps.retention.MaxAge
+	for _, policy := range ps.topicRetention {
+		if policy.MaxAge > 0 && (shortest == 0 || policy.MaxAge < shortest) {
+			shortest = policy.MaxAge
+		}
+	}
+	return shortest
+}
+
+// expireMessages compacts the topics with a MaxAge every interval until the
+// system is closed, so old messages of idle topics are released too.
+func (ps *PubSubSystem) expireMessages(interval time.Duration) {
+	ticker := time.NewTicker(max(interval, time.Millisecond))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ps.closedSignal:
+			return
+		}
+
+		ps.mutex.RLock()
+		topics := make([]*PubSubSystem, 0, len(ps.topics))
+		for _, topic := range ps.topics {
+			if topic.retention.MaxAge > 0 {
+				topics = append(topics, topic)
+			}
+		}
+		ps.mutex.RUnlock()
+
+		// Dropped messages may make room for blocked publishers
+		for _, topic := range topics {
+			topic.compact()
+			topic.spaceAvailable.Broadcast()
+		}
+	}
+}
+
+func (topic *PubSubSystem) compact() {
+	topic.mutex.Lock()
+	defer topic.mutex.Unlock()
+
+	topic.compactLocked()
+}
+
+// compactLocked drops messages no subscriber needs and enforces the retention
+// limits. The topic mutex must be held for writing.
+func (topic *PubSubSystem) compactLocked() {
+	// New subscribers start after the last published message, so only the
+	// current ones hold messages
+	keepFrom := topic.lastPublishedIndex + 1
+	for _, sub := range topic.subscribers {
+		if sub.lastProcessedIndex+1 < keepFrom {
+			keepFrom = sub.lastProcessedIndex + 1
+		}
+	}
+	topic.dropBefore(keepFrom)
+
+	policy := topic.retention
+	if policy.MaxMessages > 0 {
+		topic.dropBefore(topic.lastPublishedIndex + 1 - policy.MaxMessages)
+	}
+
+	if policy.MaxAge > 0 {
+		deadline := time.Now().Add(-policy.MaxAge)
+		for len(topic.messages) > 0 && topic.messages[0].message.Timestamp.Before(deadline) {
+			topic.dropBefore(topic.firstIndex + 1)
+		}
+	}
+
+	if policy.MaxBytes > 0 {
+		for len(topic.messages) > 0 && topic.storedBytes > policy.MaxBytes {
+			topic.dropBefore(topic.firstIndex + 1)
+		}
+	}
+}
+
+// dropBefore removes messages with indices below index.
+func (topic *PubSubSystem) dropBefore(index int) {
+	count := index - topic.firstIndex
+	if count <= 0 {
+		return
+	}
+
+	// Clear the references so the payloads can be collected before the
+	// backing array is reallocated
+	for i := 0; i < count; i++ {
+		topic.storedBytes -= topic.messages[i].size
+		topic.messages[i] = storedMessage{}
+	}
+
+	topic.messages = topic.messages[count:]
+	topic.firstIndex = index
+}
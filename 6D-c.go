@@ -0,0 +1,124 @@
+//go:build !solution
+
+package pubsub
+
+// BackpressurePolicy decides what happens when a subscriber's backlog of
+// unprocessed messages reaches its buffer size.
+type BackpressurePolicy int
+
+const (
+	// Unbounded lets the backlog grow without limit.
+	Unbounded BackpressurePolicy = iota
+	// BlockPublisher makes Publish wait until the subscriber catches up.
+	BlockPublisher
+	// DropOldest discards the oldest unprocessed message.
+	DropOldest
+	// DropNewest discards the message being published.
+	DropNewest
+)
+
+type SubscribeOption func(*Subscriber)
+
+// WithBackpressure bounds the subscriber's backlog by bufferSize messages.
+func WithBackpressure(policy BackpressurePolicy, bufferSize int) SubscribeOption {
+	return func(sub *Subscriber) {
+		if bufferSize < 1 {
+			bufferSize = 1
+		}
+		sub.backpressure = policy
+		sub.bufferSize = bufferSize
+	}
+}
+
+// Dropped returns the number of messages the subscriber never received,
+// either because of its backpressure policy or the topic retention limits.
+func (sub *Subscriber) Dropped() uint64 {
+	return sub.cursor().dropped.Load()
+}
+
+// backlog returns the number of messages waiting for the subscriber. The
+// topic mutex must be held.
+func (sub *Subscriber) backlog() int {
+	processed := sub.lastProcessedIndex
+	if processed < sub.topic.firstIndex-1 {
+		processed = sub.topic.firstIndex - 1
+	}
+
+	return sub.topic.lastPublishedIndex - processed - sub.skippedBetween(processed, sub.topic.lastPublishedIndex)
+}
+
+// skippedBetween counts messages in (from, to] dropped by DropNewest.
+func (sub *Subscriber) skippedBetween(from, to int) int {
+	count := 0
+	for _, skipped := range sub.skippedRanges {
+		low, high := max(skipped[0], from+1), min(skipped[1], to)
+		if low <= high {
+			count += high - low + 1
+		}
+	}
+	return count
+}
+
+// isSkipped reports whether the message at index was dropped by DropNewest.
+// Indices must be checked in increasing order.
+func (sub *Subscriber) isSkipped(index int) bool {
+	for len(sub.skippedRanges) > 0 && sub.skippedRanges[0][1] < index {
+		sub.skippedRanges = sub.skippedRanges[1:]
+	}
+	return len(sub.skippedRanges) > 0 && sub.skippedRanges[0][0] <= index
+}
+
+func (sub *Subscriber) skip(index int) {
+	last := len(sub.skippedRanges) - 1
+	if last >= 0 && sub.skippedRanges[last][1] == index-1 {
+		sub.skippedRanges[last][1] = index
+	} else {
+		sub.skippedRanges = append(sub.skippedRanges, [2]int{index, index})
+	}
+}
+
+// waitForBlockingSubscribers waits until every BlockPublisher subscriber has
+// room for one more message. It returns false if the topic was closed
+// meanwhile. The topic mutex must be held for writing.
+func (topic *PubSubSystem) waitForBlockingSubscribers() bool {
+	for {
+		if topic.isClosedTopic {
+			return false
+		}
+
+		full := false
+		for _, sub := range topic.subscribers {
+			if sub.backpressure == BlockPublisher && sub.backlog() >= sub.bufferSize {
+				full = true
+				break
+			}
+		}
+
+		if !full {
+			return true
+		}
+		topic.spaceAvailable.Wait()
+	}
+}
+
+// applyBackpressure drops a message from the subscriber's backlog if the
+// message just published overflowed it. The topic mutex must be held for
+// writing.
+func (sub *Subscriber) applyBackpressure() {
+	if sub.backpressure != DropOldest && sub.backpressure != DropNewest {
+		return
+	}
+	if sub.backlog() <= sub.bufferSize {
+		return
+	}
+
+	if sub.backpressure == DropNewest {
+		sub.skip(sub.topic.lastPublishedIndex)
+	} else {
+		if sub.lastProcessedIndex < sub.topic.firstIndex-1 {
+			sub.lastProcessedIndex = sub.topic.firstIndex - 1
+		}
+		sub.lastProcessedIndex++
+	}
+	sub.dropped.Add(1)
+}
@@ -0,0 +1,37 @@
+//go:build !solution
+
+package pubsub
+
+// joinQueueGroup returns the cursor shared by the members of group, creating
+// it with the options of the first member. The topic mutex must be held for
+// writing.
+func (topic *PubSubSystem) joinQueueGroup(group string, opts []SubscribeOption) *Subscriber {
+	cursor, exists := topic.queueGroups[group]
+	if !exists {
+		cursor = &Subscriber{
+			id:                 topic.nextSubscriberID,
+			lastProcessedIndex: topic.lastPublishedIndex,
+			topic:              topic,
+			groupName:          group,
+		}
+		for _, opt := range opts {
+			opt(cursor)
+		}
+		topic.nextSubscriberID++
+
+		topic.queueGroups[group] = cursor
+		topic.subscribers[cursor.id] = cursor
+	}
+
+	cursor.members++
+	return cursor
+}
+
+// cursor returns the subscriber tracking delivered messages, which is the
+// group cursor for queue group members.
+func (sub *Subscriber) cursor() *Subscriber {
+	if sub.group != nil {
+		return sub.group
+	}
+	return sub
+}
@@ -0,0 +1,129 @@
+//go:build !solution
+
+package pubsub
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"sync/atomic"
+)
+
+var (
+	ErrNoResponders = errors.New("no responders")
+	ErrClosed       = errors.New("pubsub system is closed")
+)
+
+// RequestMessage is delivered to handlers of subjects used with Request.
+// Handlers answer with Respond.
+type RequestMessage struct {
+	Data    interface{}
+	ReplyTo string
+
+	ps *PubSubSystem
+}
+
+// Respond publishes reply to the inbox of the requester.
+func (msg *RequestMessage) Respond(reply interface{}) error {
+	return msg.ps.Publish(msg.ReplyTo, reply)
+}
+
+var (
+	inboxPrefix  = newInboxPrefix()
+	inboxCounter atomic.Uint64
+)
+
+func newInboxPrefix() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return "_INBOX." + hex.EncodeToString(buf)
+}
+
+func newInbox() string {
+	return inboxPrefix + "." + strconv.FormatUint(inboxCounter.Add(1), 10)
+}
+
+// Request publishes msg wrapped in a RequestMessage and waits for the first
+// reply, the context deadline or the system shutdown.
+func (ps *PubSubSystem) Request(ctx context.Context, subject string, msg interface{}) (interface{}, error) {
+	if err := validateSubject(subject, false); err != nil {
+		return nil, err
+	}
+
+	if !ps.hasSubscribers(subject) {
+		return nil, ErrNoResponders
+	}
+
+	replies := make(chan interface{}, 1)
+	inbox := newInbox()
+	sub, err := ps.Subscribe(inbox, func(reply interface{}) {
+		select {
+		case replies <- reply:
+		default:
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer sub.Unsubscribe()
+
+	err = ps.Publish(subject, &RequestMessage{Data: msg, ReplyTo: inbox, ps: ps})
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case reply := <-replies:
+		return reply, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-ps.closedSignal:
+		return nil, ErrClosed
+	}
+}
+
+func (ps *PubSubSystem) hasSubscribers(subject string) bool {
+	ps.mutex.RLock()
+	defer ps.mutex.RUnlock()
+
+	for pattern, topic := range ps.topics {
+		if !matchSubject(pattern, subject) {
+			continue
+		}
+
+		topic.mutex.RLock()
+		count := len(topic.subscribers)
+		topic.mutex.RUnlock()
+
+		if count > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// removeEmptyTopics forgets topics without subscribers, such as the inboxes
+// of finished requests. It runs once the number of topics doubles, so the
+// cost is amortized over Subscribe calls. The mutex must be held for writing.
+func (ps *PubSubSystem) removeEmptyTopics() {
+	if len(ps.topics) < ps.sweepThreshold {
+		return
+	}
+
+	for subject, topic := range ps.topics {
+		topic.mutex.RLock()
+		empty := len(topic.subscribers) == 0
+		topic.mutex.RUnlock()
+
+		if empty {
+			delete(ps.topics, subject)
+		}
+	}
+
+	ps.sweepThreshold = 2*len(ps.topics) + 16
+}
@@ -0,0 +1,245 @@
+//go:build !solution
+
+package pubsub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Codec turns messages of durable subjects into bytes and back.
+type Codec interface {
+	Encode(msg interface{}) ([]byte, error)
+	Decode(data []byte) (interface{}, error)
+}
+
+// JSONCodec is the default codec. Replayed messages are decoded into generic
+// JSON values such as map[string]interface{} and float64.
+type JSONCodec struct{}
+
+func (JSONCodec) Encode(msg interface{}) ([]byte, error) {
+	return json.Marshal(msg)
+}
+
+func (JSONCodec) Decode(data []byte) (interface{}, error) {
+	var msg interface{}
+	err := json.Unmarshal(data, &msg)
+	return msg, err
+}
+
+// WithDurableSubjects stores messages published on the given exact subjects
+// in segment files under dir, so they survive restarts and can be replayed.
+func WithDurableSubjects(dir string, subjects ...string) Option {
+	return func(ps *PubSubSystem) {
+		ps.storageDir = dir
+		for _, subject := range subjects {
+			ps.durableSubjects[subject] = true
+		}
+	}
+}
+
+// WithCodec sets the codec of durable subjects.
+func WithCodec(codec Codec) Option {
+	return func(ps *PubSubSystem) {
+		ps.codec = codec
+	}
+}
+
+// WithMaxSegmentBytes sets the size after which a new segment file is started.
+func WithMaxSegmentBytes(size int64) Option {
+	return func(ps *PubSubSystem) {
+		ps.maxSegmentBytes = size
+	}
+}
+
+const startAtNew = -1
+
+// StartAt replays a durable subject from the message with the given offset.
+func StartAt(offset int64) SubscribeOption {
+	return func(sub *Subscriber) {
+		sub.startOffset = offset
+	}
+}
+
+// StartAtEarliest replays a durable subject from its first message.
+func StartAtEarliest() SubscribeOption {
+	return StartAt(0)
+}
+
+// StartAtNew delivers only messages published after subscribing, which is
+// the default.
+func StartAtNew() SubscribeOption {
+	return StartAt(startAtNew)
+}
+
+// store returns the store of a durable subject, opening it on first use, or
+// nil for other subjects.
+func (ps *PubSubSystem) store(subject string) (*topicStore, error) {
+	if !ps.durableSubjects[subject] {
+		return nil, nil
+	}
+
+	ps.storesMutex.Lock()
+	defer ps.storesMutex.Unlock()
+
+	if store, ok := ps.stores[subject]; ok {
+		return store, nil
+	}
+
+	store, err := openTopicStore(filepath.Join(ps.storageDir, url.PathEscape(subject)), ps.maxSegmentBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	ps.stores[subject] = store
+	return store, nil
+}
+
+// publishMutex returns the mutex serializing publishers of a durable
+// subject, or nil for other subjects.
+func (ps *PubSubSystem) publishMutex(subject string) *sync.Mutex {
+	if !ps.durableSubjects[subject] {
+		return nil
+	}
+
+	ps.storesMutex.Lock()
+	defer ps.storesMutex.Unlock()
+
+	publishMutex, ok := ps.publishMutexes[subject]
+	if !ok {
+		publishMutex = &sync.Mutex{}
+		ps.publishMutexes[subject] = publishMutex
+	}
+	return publishMutex
+}
+
+// persistedMessage is the record of a message in a store. The sequence is
+// implied by the offset.
+type persistedMessage struct {
+	ID        string            `json:"id"`
+	Timestamp time.Time         `json:"timestamp"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Data      []byte            `json:"data"`
+}
+
+// persist appends message to the store of a durable subject.
+func (ps *PubSubSystem) persist(message *Message) error {
+	store, err := ps.store(message.Subject)
+	if err != nil || store == nil {
+		return err
+	}
+
+	data, err := ps.codec.Encode(message.Data)
+	if err != nil {
+		return err
+	}
+
+	record, err := json.Marshal(persistedMessage{
+		ID:        message.ID,
+		Timestamp: message.Timestamp,
+		Headers:   message.Headers,
+		Data:      data,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = store.append(record)
+	return err
+}
+
+// prepareReplay remembers which stored messages the subscriber has to replay
+// before the live ones. The publish mutex of the subject must be held, so no
+// message is stored or delivered in between.
+func (ps *PubSubSystem) prepareReplay(sub *Subscriber, subject string) error {
+	if sub.startOffset == startAtNew {
+		return nil
+	}
+
+	store, err := ps.store(subject)
+	if err != nil {
+		return err
+	}
+	if store == nil {
+		return errors.New("replay requires a durable subject")
+	}
+
+	sub.replayStore = store
+	sub.replayTo = store.offset()
+	if sub.startOffset > sub.replayTo {
+		sub.startOffset = sub.replayTo
+	}
+
+	return nil
+}
+
+// replay delivers the stored messages published before subscribing. It stops
+// early on Unsubscribe and returns false if a panicking handler closed the
+// subscription. Unreadable records are counted as dropped and reported to
+// the error handler.
+func (sub *Subscriber) replay() bool {
+	if sub.replayStore == nil {
+		return true
+	}
+
+	handled := true
+	err := sub.replayStore.read(sub.startOffset, sub.replayTo, func(offset int64, data []byte) bool {
+		select {
+		case <-sub.unsubscribeSignal:
+			return false
+		default:
+		}
+
+		var record persistedMessage
+		if err := json.Unmarshal(data, &record); err != nil {
+			sub.dropped.Add(1)
+			sub.reportError(fmt.Errorf("replay of %q at offset %d: %w", sub.subject, offset, err))
+			return true
+		}
+
+		payload, err := sub.codec.Decode(record.Data)
+		if err != nil {
+			sub.dropped.Add(1)
+			sub.reportError(fmt.Errorf("replay of %q at offset %d: %w", sub.subject, offset, err))
+			return true
+		}
+
+		handled = sub.handle(sub.delivered(&Message{
+			ID:        record.ID,
+			Subject:   sub.subject,
+			Timestamp: record.Timestamp,
+			Sequence:  offset + 1,
+			Headers:   record.Headers,
+			Data:      payload,
+		}))
+		return handled
+	})
+	if err != nil {
+		sub.reportError(fmt.Errorf("replay of %q: %w", sub.subject, err))
+	}
+
+	return handled
+}
+
+func (sub *Subscriber) reportError(err error) {
+	if sub.errorHandler != nil {
+		sub.errorHandler(err)
+	}
+}
+
+func (ps *PubSubSystem) closeStores() error {
+	ps.storesMutex.Lock()
+	defer ps.storesMutex.Unlock()
+
+	var errs []error
+	for _, store := range ps.stores {
+		errs = append(errs, store.close())
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,275 @@
+//go:build !solution
+
+package pubsub
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	segmentSuffix          = ".seg"
+	recordHeaderSize       = 4
+	defaultMaxSegmentBytes = 16 << 20
+)
+
+type segment struct {
+	baseOffset int64
+	path       string
+	count      int64
+	size       int64
+}
+
+// topicStore is an append-only log of a durable subject split into segment
+// files. Each record is the payload length as a big endian uint32 followed by
+// the encoded message.
+type topicStore struct {
+	mutex           sync.Mutex
+	dir             string
+	segments        []*segment
+	active          *os.File
+	nextOffset      int64
+	maxSegmentBytes int64
+}
+
+func openTopicStore(dir string, maxSegmentBytes int64) (*topicStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	store := &topicStore{dir: dir, maxSegmentBytes: maxSegmentBytes}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+
+		var baseOffset int64
+		if _, err := fmt.Sscanf(name, "%d"+segmentSuffix, &baseOffset); err != nil {
+			continue
+		}
+		store.segments = append(store.segments, &segment{baseOffset: baseOffset, path: filepath.Join(dir, name)})
+	}
+
+	sort.Slice(store.segments, func(i, j int) bool {
+		return store.segments[i].baseOffset < store.segments[j].baseOffset
+	})
+
+	// Count records of every segment, the last one may end with a record
+	// torn by a crash
+	for i, seg := range store.segments {
+		if err := seg.scan(i == len(store.segments)-1); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(store.segments) == 0 {
+		if err := store.roll(); err != nil {
+			return nil, err
+		}
+	} else {
+		last := store.segments[len(store.segments)-1]
+		store.nextOffset = last.baseOffset + last.count
+
+		store.active, err = os.OpenFile(last.path, os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return store, nil
+}
+
+// scan counts the complete records of the segment, truncating a partial
+// trailing record if repair is set.
+func (seg *segment) scan(repair bool) error {
+	file, err := os.Open(seg.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	header := make([]byte, recordHeaderSize)
+	for {
+		_, err := io.ReadFull(file, header)
+		if err == io.EOF {
+			return nil
+		}
+
+		if err == nil {
+			length := int64(binary.BigEndian.Uint32(header))
+			var skipped int64
+			skipped, err = io.CopyN(io.Discard, file, length)
+			if err == nil {
+				seg.count++
+				seg.size += recordHeaderSize + skipped
+				continue
+			}
+		}
+
+		if !errors.Is(err, io.ErrUnexpectedEOF) && err != io.EOF {
+			return err
+		}
+		if !repair {
+			return fmt.Errorf("segment %s is corrupted", seg.path)
+		}
+		return os.Truncate(seg.path, seg.size)
+	}
+}
+
+// roll starts a new segment at the next offset.
+func (store *topicStore) roll() error {
+	if store.active != nil {
+		if err := store.active.Close(); err != nil {
+			return err
+		}
+	}
+
+	seg := &segment{
+		baseOffset: store.nextOffset,
+		path:       filepath.Join(store.dir, fmt.Sprintf("%020d%s", store.nextOffset, segmentSuffix)),
+	}
+
+	file, err := os.OpenFile(seg.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	store.active = file
+	store.segments = append(store.segments, seg)
+	return nil
+}
+
+// append writes an encoded message and returns its offset.
+func (store *topicStore) append(data []byte) (int64, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if store.active == nil {
+		return 0, ErrClosed
+	}
+
+	last := store.segments[len(store.segments)-1]
+	if last.size > 0 && last.size+recordHeaderSize+int64(len(data)) > store.maxSegmentBytes {
+		if err := store.roll(); err != nil {
+			return 0, err
+		}
+		last = store.segments[len(store.segments)-1]
+	}
+
+	// Write the record at once, readers never look past nextOffset
+	record := make([]byte, recordHeaderSize+len(data))
+	binary.BigEndian.PutUint32(record, uint32(len(data)))
+	copy(record[recordHeaderSize:], data)
+
+	if _, err := store.active.Write(record); err != nil {
+		return 0, err
+	}
+
+	last.count++
+	last.size += int64(len(record))
+	store.nextOffset++
+
+	return store.nextOffset - 1, nil
+}
+
+func (store *topicStore) offset() int64 {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	return store.nextOffset
+}
+
+// read calls f for every record in [from, to) until f returns false.
+func (store *topicStore) read(from, to int64, f func(offset int64, data []byte) bool) error {
+	store.mutex.Lock()
+	segments := append([]*segment(nil), store.segments...)
+	store.mutex.Unlock()
+
+	offset := from
+	for i, seg := range segments {
+		if offset >= to {
+			return nil
+		}
+		if i+1 < len(segments) && segments[i+1].baseOffset <= offset {
+			continue
+		}
+
+		next, err := seg.read(offset, to, f)
+		if err != nil || next < 0 {
+			return err
+		}
+		offset = next
+	}
+
+	return nil
+}
+
+// read calls f for records of the segment in [from, to) and returns the
+// offset to continue from, or -1 if f asked to stop.
+func (seg *segment) read(from, to int64, f func(offset int64, data []byte) bool) (int64, error) {
+	file, err := os.Open(seg.path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	header := make([]byte, recordHeaderSize)
+	for offset := seg.baseOffset; offset < to; offset++ {
+		if _, err := io.ReadFull(file, header); err != nil {
+			if err == io.EOF {
+				return offset, nil
+			}
+			return 0, err
+		}
+
+		length := int64(binary.BigEndian.Uint32(header))
+		if offset < from {
+			if _, err := file.Seek(length, io.SeekCurrent); err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		// A corrupted length must not allocate more than the file holds
+		var data bytes.Buffer
+		if _, err := io.CopyN(&data, file, length); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+
+		if !f(offset, data.Bytes()) {
+			return -1, nil
+		}
+	}
+
+	return to, nil
+}
+
+func (store *topicStore) close() error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if store.active == nil {
+		return nil
+	}
+
+	err := store.active.Close()
+	store.active = nil
+	return err
+}
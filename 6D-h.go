@@ -0,0 +1,204 @@
+//go:build !solution
+
+package pubsub
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// The line protocol spoken between Server and Client. Payloads are single
+// line JSON documents.
+//
+//	client: SUB <subject> <sid>
+//	client: UNSUB <sid>
+//	client: PUB <subject> <payload>
+//	server: ACK <sid>
+//	server: NACK <sid> <message>
+//	server: MSG <sid> <payload>
+//	server: ERR <message>
+//
+// Every SUB is answered by ACK or NACK, other failures by ERR.
+
+// Server exposes a PubSub to clients over TCP.
+type Server struct {
+	ps    PubSub
+	codec Codec
+
+	mutex    sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]bool
+	isClosed bool
+}
+
+func NewServer(ps PubSub) *Server {
+	return &Server{
+		ps:    ps,
+		codec: JSONCodec{},
+		conns: make(map[net.Conn]bool),
+	}
+}
+
+// Serve accepts connections on listener until Close is called.
+func (s *Server) Serve(listener net.Listener) error {
+	s.mutex.Lock()
+	if s.isClosed {
+		s.mutex.Unlock()
+		return ErrClosed
+	}
+	s.listener = listener
+	s.mutex.Unlock()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			s.mutex.Lock()
+			defer s.mutex.Unlock()
+			if s.isClosed {
+				return nil
+			}
+			return err
+		}
+
+		s.mutex.Lock()
+		s.conns[conn] = true
+		s.mutex.Unlock()
+
+		go s.handleConn(conn)
+	}
+}
+
+// Close stops accepting connections and disconnects every client. The
+// underlying PubSub is left open.
+func (s *Server) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.isClosed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+
+	if s.listener != nil {
+		return s.listener.Close()
+	}
+	return nil
+}
+
+type serverConn struct {
+	server        *Server
+	conn          net.Conn
+	writeMutex    sync.Mutex
+	subscriptions map[string]Subscription
+}
+
+func (s *Server) handleConn(conn net.Conn) {
+	sc := &serverConn{server: s, conn: conn, subscriptions: make(map[string]Subscription)}
+
+	defer func() {
+		for _, sub := range sc.subscriptions {
+			sub.Unsubscribe()
+		}
+
+		s.mutex.Lock()
+		delete(s.conns, conn)
+		s.mutex.Unlock()
+
+		conn.Close()
+	}()
+
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		if err := sc.handleLine(strings.TrimRight(line, "\r\n")); err != nil {
+			if sc.writeLine("ERR "+err.Error()) != nil {
+				return
+			}
+		}
+	}
+}
+
+func (sc *serverConn) handleLine(line string) error {
+	command, args, _ := strings.Cut(line, " ")
+
+	switch command {
+	case "SUB":
+		fields := strings.Fields(args)
+		if len(fields) != 2 {
+			return errors.New("usage: SUB <subject> <sid>")
+		}
+		subject, sid := fields[0], fields[1]
+
+		// A broken connection shows up on the next read
+		if err := sc.subscribe(subject, sid); err != nil {
+			_ = sc.writeLine("NACK " + sid + " " + err.Error())
+			return nil
+		}
+		_ = sc.writeLine("ACK " + sid)
+
+	case "UNSUB":
+		sid := strings.TrimSpace(args)
+		sub, ok := sc.subscriptions[sid]
+		if !ok {
+			return fmt.Errorf("unknown sid %s", sid)
+		}
+		sub.Unsubscribe()
+		delete(sc.subscriptions, sid)
+
+	case "PUB":
+		subject, payload, found := strings.Cut(args, " ")
+		if !found {
+			return errors.New("usage: PUB <subject> <payload>")
+		}
+
+		msg, err := sc.server.codec.Decode([]byte(payload))
+		if err != nil {
+			return err
+		}
+		return sc.server.ps.Publish(subject, msg)
+
+	default:
+		return fmt.Errorf("unknown command %q", command)
+	}
+
+	return nil
+}
+
+func (sc *serverConn) subscribe(subject, sid string) error {
+	if _, ok := sc.subscriptions[sid]; ok {
+		return fmt.Errorf("sid %s is in use", sid)
+	}
+
+	sub, err := sc.server.ps.Subscribe(subject, func(msg interface{}) {
+		data, err := sc.server.codec.Encode(msg)
+		if err != nil {
+			_ = sc.writeLine("ERR " + err.Error())
+			return
+		}
+		_ = sc.writeLine("MSG " + sid + " " + string(data))
+	})
+	if err != nil {
+		return err
+	}
+
+	sc.subscriptions[sid] = sub
+	return nil
+}
+
+// writeLine is called from subscriber goroutines as well, so writes are
+// serialized.
+func (sc *serverConn) writeLine(line string) error {
+	sc.writeMutex.Lock()
+	defer sc.writeMutex.Unlock()
+
+	_, err := sc.conn.Write([]byte(line + "\n"))
+	return err
+}
@@ -0,0 +1,217 @@
+//go:build !solution
+
+package pubsub
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var _ PubSub = (*Client)(nil)
+
+// Client is a PubSub backed by a Server. Messages travel as JSON, so
+// handlers receive generic JSON values such as map[string]interface{} and
+// float64.
+type Client struct {
+	conn    net.Conn
+	codec   Codec
+	nextSID int
+
+	// Received messages are dispatched through a local system, which keeps
+	// handlers of a subscription sequential and off the reading goroutine
+	local PubSub
+
+	writeMutex sync.Mutex
+	mutex      sync.Mutex
+	serverErr  error
+	// pending holds SUB requests waiting for ACK or NACK
+	pending    map[string]chan error
+	readerDone chan struct{}
+	closeOnce  sync.Once
+	closeErr   error
+}
+
+type clientSubscription struct {
+	client *Client
+	sid    string
+	local  Subscription
+	once   sync.Once
+}
+
+// Dial connects to a Server listening on addr.
+func Dial(addr string) (*Client, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		conn:       conn,
+		codec:      JSONCodec{},
+		local:      NewPubSub(),
+		pending:    make(map[string]chan error),
+		readerDone: make(chan struct{}),
+	}
+	go c.read()
+
+	return c, nil
+}
+
+// Subscribe waits for the server to accept the subscription.
+func (c *Client) Subscribe(subject string, handler MsgHandler) (Subscription, error) {
+	if err := validateSubject(subject, true); err != nil {
+		return nil, err
+	}
+
+	reply := make(chan error, 1)
+	c.mutex.Lock()
+	sid := strconv.Itoa(c.nextSID)
+	c.nextSID++
+	c.pending[sid] = reply
+	c.mutex.Unlock()
+
+	defer func() {
+		c.mutex.Lock()
+		delete(c.pending, sid)
+		c.mutex.Unlock()
+	}()
+
+	local, err := c.local.Subscribe(localSubject(sid), handler)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.writeLine("SUB " + subject + " " + sid); err != nil {
+		local.Unsubscribe()
+		return nil, err
+	}
+
+	select {
+	case err = <-reply:
+	case <-c.readerDone:
+		err = errors.New("connection closed")
+	}
+	if err != nil {
+		local.Unsubscribe()
+		return nil, err
+	}
+
+	return &clientSubscription{client: c, sid: sid, local: local}, nil
+}
+
+func (sub *clientSubscription) Unsubscribe() {
+	sub.once.Do(func() {
+		_ = sub.client.writeLine("UNSUB " + sub.sid)
+		sub.local.Unsubscribe()
+	})
+}
+
+func (c *Client) Publish(subject string, msg interface{}) error {
+	if err := validateSubject(subject, false); err != nil {
+		return err
+	}
+
+	data, err := c.codec.Encode(msg)
+	if err != nil {
+		return err
+	}
+
+	return c.writeLine("PUB " + subject + " " + string(data))
+}
+
+// Close disconnects from the server and waits for handlers to process the
+// messages already received. It returns the last error reported by the
+// server, if any. Closing again returns the same error.
+func (c *Client) Close(ctx context.Context) error {
+	c.closeOnce.Do(func() {
+		c.closeErr = c.close(ctx)
+	})
+	return c.closeErr
+}
+
+func (c *Client) close(ctx context.Context) error {
+	err := c.conn.Close()
+	<-c.readerDone
+
+	if closeErr := c.local.Close(ctx); closeErr != nil {
+		return closeErr
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.serverErr != nil {
+		return c.serverErr
+	}
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+func (c *Client) read() {
+	defer close(c.readerDone)
+
+	reader := bufio.NewReader(c.conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		command, args, _ := strings.Cut(strings.TrimRight(line, "\r\n"), " ")
+		switch command {
+		case "MSG":
+			sid, payload, _ := strings.Cut(args, " ")
+			msg, err := c.codec.Decode([]byte(payload))
+			if err != nil {
+				c.setServerErr(err)
+				continue
+			}
+			_ = c.local.Publish(localSubject(sid), msg)
+
+		case "ACK":
+			c.reply(args, nil)
+
+		case "NACK":
+			sid, message, _ := strings.Cut(args, " ")
+			c.reply(sid, errors.New(message))
+
+		case "ERR":
+			c.setServerErr(errors.New(args))
+		}
+	}
+}
+
+func (c *Client) reply(sid string, err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if reply, ok := c.pending[sid]; ok {
+		reply <- err
+	}
+}
+
+func (c *Client) setServerErr(err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.serverErr = err
+}
+
+func (c *Client) writeLine(line string) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	_, err := c.conn.Write([]byte(line + "\n"))
+	return err
+}
+
+func localSubject(sid string) string {
+	return "sid." + sid
+}
@@ -0,0 +1,130 @@
+//go:build !solution
+
+package pubsub
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func startServer(t *testing.T, ps PubSub) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewServer(ps)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(func() { _ = server.Close() })
+
+	return listener.Addr().String()
+}
+
+func TestClientRoundTrip(t *testing.T) {
+	addr := startServer(t, NewPubSub())
+
+	client, err := Dial(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	received := make(chan interface{}, 10)
+	sub, err := client.Subscribe("orders.*", func(msg interface{}) {
+		received <- msg
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Publish("orders.new", map[string]interface{}{"id": 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case msg := <-received:
+		if msg.(map[string]interface{})["id"] != 1.0 {
+			t.Fatalf("unexpected message %v", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("message was not delivered")
+	}
+
+	// Nothing arrives after Unsubscribe, a later subscription proves the
+	// server has handled UNSUB before the publish
+	sub.Unsubscribe()
+	done := make(chan interface{}, 1)
+	if _, err := client.Subscribe("orders.new", func(msg interface{}) { done <- msg }); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Publish("orders.new", "late"); err != nil {
+		t.Fatal(err)
+	}
+
+	<-done
+	select {
+	case msg := <-received:
+		t.Fatalf("message %v delivered after Unsubscribe", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// Closing twice, e.g. deferred and explicit, is harmless
+	for i := 0; i < 2; i++ {
+		if err := client.Close(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestClientSubscribeRejected(t *testing.T) {
+	ps := NewPubSub()
+	addr := startServer(t, ps)
+
+	client, err := Dial(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close(context.Background())
+
+	if _, err := client.Subscribe("orders..new", func(interface{}) {}); err == nil {
+		t.Fatal("invalid subject was accepted")
+	}
+
+	// The server refuses subscriptions once its PubSub is closed
+	if err := ps.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Subscribe("orders", func(interface{}) {}); err == nil {
+		t.Fatal("subscription refused by the server was accepted")
+	}
+}
+
+func TestServerNacksInvalidSubject(t *testing.T) {
+	addr := startServer(t, NewPubSub())
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("SUB orders..new 1\nSUB orders 2\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := bufio.NewReader(conn)
+	for _, want := range []string{"NACK 1 ", "ACK 2"} {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(line, want) {
+			t.Fatalf("got %q, want %q", line, want)
+		}
+	}
+}
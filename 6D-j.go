@@ -0,0 +1,51 @@
+//go:build !solution
+
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"time"
+
+	"gitlab.com/slon/shad-go/pubsub"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:4222", "Address to listen on.")
+	flag.Parse()
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatalf("listen: %v", err)
+	}
+
+	ps := pubsub.NewPubSub()
+	server := pubsub.NewServer(ps)
+
+	// Stop on interrupt, letting handlers finish for a while
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt)
+		<-signals
+
+		if err := server.Close(); err != nil {
+			log.Printf("close server: %v", err)
+		}
+	}()
+
+	log.Printf("listening on %s", listener.Addr())
+	if err := server.Serve(listener); err != nil {
+		log.Fatalf("serve: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := ps.Close(ctx); err != nil {
+		log.Fatalf("close pubsub: %v", err)
+	}
+}
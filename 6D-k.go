@@ -0,0 +1,80 @@
+//go:build !solution
+
+package pubsub
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// PanicPolicy decides what happens to a subscription whose handler panics.
+type PanicPolicy int
+
+const (
+	// ContinueOnPanic skips the message and keeps the subscription.
+	ContinueOnPanic PanicPolicy = iota
+	// CloseOnPanic unsubscribes the handler.
+	CloseOnPanic
+)
+
+// HandlerPanicError describes a panic recovered from a handler.
+type HandlerPanicError struct {
+	Subject string
+	Value   interface{}
+	Stack   []byte
+}
+
+func (err *HandlerPanicError) Error() string {
+	return fmt.Sprintf("handler of %q panicked: %v", err.Subject, err.Value)
+}
+
+// WithErrorHandler sets a callback receiving panics recovered from handlers
+// and errors reading durable subjects during replay.
+func WithErrorHandler(handler func(err error)) Option {
+	return func(ps *PubSubSystem) {
+		ps.errorHandler = handler
+	}
+}
+
+// WithPanicPolicy sets what happens to subscriptions whose handler panics.
+func WithPanicPolicy(policy PanicPolicy) Option {
+	return func(ps *PubSubSystem) {
+		ps.panicPolicy = policy
+	}
+}
+
+// handle calls the handler, recovering a panic. It returns false if the
+// subscription has to be closed.
+func (sub *Subscriber) handle(message interface{}) (ok bool) {
+	defer func() {
+		value := recover()
+		if value == nil {
+			return
+		}
+
+		if sub.errorHandler != nil {
+			sub.errorHandler(&HandlerPanicError{Subject: sub.subject, Value: value, Stack: debug.Stack()})
+		}
+		ok = sub.panicPolicy != CloseOnPanic
+	}()
+
+	start := time.Now()
+	defer func() {
+		sub.recordLatency(time.Since(start))
+	}()
+
+	sub.messageHandler(message)
+	return true
+}
+
+// closeAfterPanic unsubscribes from the listening goroutine and lets a
+// concurrent Close stop waiting for it.
+func (sub *Subscriber) closeAfterPanic() {
+	sub.Unsubscribe()
+
+	select {
+	case sub.finishedSignal <- struct{}{}:
+	default:
+	}
+}
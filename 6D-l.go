@@ -0,0 +1,110 @@
+//go:build !solution
+
+package pubsub
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// TopicStats describes a topic, i.e. the subscriptions sharing a subject.
+type TopicStats struct {
+	Subject     string            `json:"subject"`
+	Published   int               `json:"published"`
+	Retained    int               `json:"retained"`
+	Subscribers []SubscriberStats `json:"subscribers"`
+}
+
+// SubscriberStats describes a subscriber or a whole queue group. Lag is the
+// number of published messages the subscriber has not taken yet.
+type SubscriberStats struct {
+	ID         int           `json:"id"`
+	Group      string        `json:"group,omitempty"`
+	Lag        int           `json:"lag"`
+	Dropped    uint64        `json:"dropped"`
+	Handled    uint64        `json:"handled"`
+	AvgLatency time.Duration `json:"avg_latency_ns"`
+	MaxLatency time.Duration `json:"max_latency_ns"`
+}
+
+// Stats returns a snapshot of every topic ordered by subject.
+func (ps *PubSubSystem) Stats() []TopicStats {
+	ps.mutex.RLock()
+	defer ps.mutex.RUnlock()
+
+	stats := make([]TopicStats, 0, len(ps.topics))
+	for subject, topic := range ps.topics {
+		stats = append(stats, topic.stats(subject))
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Subject < stats[j].Subject
+	})
+
+	return stats
+}
+
+func (topic *PubSubSystem) stats(subject string) TopicStats {
+	// Subscribers advance under the read lock, so reading their progress
+	// needs the write lock
+	topic.mutex.Lock()
+	defer topic.mutex.Unlock()
+
+	stats := TopicStats{
+		Subject:     subject,
+		Published:   topic.lastPublishedIndex + 1,
+		Retained:    len(topic.messages),
+		Subscribers: make([]SubscriberStats, 0, len(topic.subscribers)),
+	}
+
+	for _, sub := range topic.subscribers {
+		handled := sub.handled.Load()
+
+		subStats := SubscriberStats{
+			ID:         sub.id,
+			Group:      sub.groupName,
+			Lag:        topic.lastPublishedIndex - sub.lastProcessedIndex,
+			Dropped:    sub.dropped.Load(),
+			Handled:    handled,
+			MaxLatency: time.Duration(sub.maxLatency.Load()),
+		}
+		if handled > 0 {
+			subStats.AvgLatency = time.Duration(sub.totalLatency.Load() / handled)
+		}
+
+		stats.Subscribers = append(stats.Subscribers, subStats)
+	}
+
+	sort.Slice(stats.Subscribers, func(i, j int) bool {
+		return stats.Subscribers[i].ID < stats.Subscribers[j].ID
+	})
+
+	return stats
+}
+
+// recordLatency accounts a handled message. Queue group members record into
+// the group cursor.
+func (sub *Subscriber) recordLatency(latency time.Duration) {
+	cursor := sub.cursor()
+	cursor.handled.Add(1)
+	cursor.totalLatency.Add(uint64(latency))
+
+	for {
+		current := cursor.maxLatency.Load()
+		if uint64(latency) <= current || cursor.maxLatency.CompareAndSwap(current, uint64(latency)) {
+			return
+		}
+	}
+}
+
+// StatsHandler serves Stats as JSON.
+func (ps *PubSubSystem) StatsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(ps.Stats()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
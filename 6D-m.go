@@ -0,0 +1,70 @@
+//go:build !solution
+
+package pubsub
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Topic is a typed view of a subject on any PubSub.
+type Topic[T any] struct {
+	ps      PubSub
+	subject string
+
+	// OnDecodeFailure is called with messages that are not of type T and
+	// cannot be decoded into it. Such messages are skipped.
+	OnDecodeFailure func(msg interface{}, err error)
+}
+
+func NewTopic[T any](ps PubSub, subject string) *Topic[T] {
+	return &Topic[T]{ps: ps, subject: subject}
+}
+
+func (t *Topic[T]) Publish(msg T) error {
+	return t.ps.Publish(t.subject, msg)
+}
+
+func (t *Topic[T]) Subscribe(handler func(msg T)) (Subscription, error) {
+	return t.ps.Subscribe(t.subject, func(msg interface{}) {
+		typed, err := decode[T](msg)
+		if err != nil {
+			if t.OnDecodeFailure != nil {
+				t.OnDecodeFailure(msg, err)
+			}
+			return
+		}
+
+		handler(typed)
+	})
+}
+
+// decode converts msg to T. Only generic JSON values, which messages from a
+// Client or a durable replay with JSONCodec are, get converted back via JSON,
+// and fields unknown to T fail the conversion.
+func decode[T any](msg interface{}) (T, error) {
+	if typed, ok := msg.(T); ok {
+		return typed, nil
+	}
+
+	var typed T
+	switch msg.(type) {
+	case map[string]interface{}, []interface{}, float64, string, bool:
+	default:
+		return typed, fmt.Errorf("message of type %T is not %T", msg, typed)
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return typed, fmt.Errorf("message of type %T is not %T: %w", msg, typed, err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&typed); err != nil {
+		return typed, fmt.Errorf("message of type %T is not %T: %w", msg, typed, err)
+	}
+
+	return typed, nil
+}
@@ -0,0 +1,75 @@
+//go:build !solution
+
+package pubsub
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Message is the envelope of a published message. Subscribers created with
+// WithEnvelopes receive *Message instead of the bare payload. Envelopes are
+// shared between subscribers and must not be modified.
+type Message struct {
+	// ID is unique across processes
+	ID        string
+	Subject   string
+	Timestamp time.Time
+	// Sequence is the 1-based position of the message in the topic of the
+	// subscription. For durable subjects it equals the stored offset plus one.
+	Sequence int64
+	Headers  map[string]string
+	Data     interface{}
+}
+
+var (
+	messageIDPrefix  = newMessageIDPrefix()
+	messageIDCounter atomic.Uint64
+)
+
+func newMessageIDPrefix() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
+
+func newMessageID() string {
+	return messageIDPrefix + "-" + strconv.FormatUint(messageIDCounter.Add(1), 10)
+}
+
+// WithEnvelopes makes the handler receive *Message with the payload in Data.
+func WithEnvelopes() SubscribeOption {
+	return func(sub *Subscriber) {
+		sub.envelopes = true
+	}
+}
+
+// PublishWithHeaders publishes msg with headers and returns the ID of the
+// message.
+func (ps *PubSubSystem) PublishWithHeaders(subject string, msg interface{}, headers map[string]string) (string, error) {
+	message := &Message{
+		ID:        newMessageID(),
+		Subject:   subject,
+		Timestamp: time.Now(),
+		Headers:   headers,
+		Data:      msg,
+	}
+
+	if err := ps.publishMessage(message); err != nil {
+		return "", err
+	}
+	return message.ID, nil
+}
+
+// delivered returns what the handler of sub receives for message.
+func (sub *Subscriber) delivered(message *Message) interface{} {
+	if sub.envelopes {
+		return message
+	}
+	return message.Data
+}
@@ -6,14 +6,43 @@ import (
 	"context"
 	"errors"
 	"sync"
+	"sync/atomic"
 )
 
 type Subscriber struct {
 	id                 int
+	subject            string
 	messageHandler     MsgHandler
 	lastProcessedIndex int
 	topic              *PubSubSystem
 
+	backpressure  BackpressurePolicy
+	bufferSize    int
+	skippedRanges [][2]int
+	dropped       atomic.Uint64
+
+	// Queue group members share the cursor of their group
+	group       *Subscriber
+	groupName   string
+	members     int
+	cursorMutex sync.Mutex
+
+	// Durable subjects replay stored messages first
+	startOffset int64
+	replayStore *topicStore
+	replayTo    int64
+	codec       Codec
+
+	errorHandler    func(err error)
+	panicPolicy     PanicPolicy
+	unsubscribeOnce sync.Once
+
+	envelopes bool
+
+	handled      atomic.Uint64
+	totalLatency atomic.Uint64
+	maxLatency   atomic.Uint64
+
 	unsubscribeSignal chan struct{}
 	closeSignal       chan struct{}
 	notifySignal      chan struct{}
@@ -21,11 +50,28 @@ type Subscriber struct {
 }
 
 func (sub *Subscriber) Unsubscribe() {
-	close(sub.topic.unsubscribeChannels[sub.id])
+	sub.unsubscribeOnce.Do(sub.unsubscribe)
+}
+
+func (sub *Subscriber) unsubscribe() {
+	close(sub.unsubscribeSignal)
 
 	sub.topic.mutex.Lock()
+	delete(sub.topic.unsubscribeChannels, sub.id)
 	delete(sub.topic.subscriberChannels, sub.id)
+	delete(sub.topic.subscribers, sub.id)
+	if sub.group != nil {
+		sub.group.members--
+		if sub.group.members == 0 {
+			delete(sub.topic.subscribers, sub.group.id)
+			delete(sub.topic.queueGroups, sub.group.groupName)
+		}
+	}
+	sub.topic.compactLocked()
 	sub.topic.mutex.Unlock()
+
+	// Publishers waiting for this subscriber may proceed
+	sub.topic.spaceAvailable.Broadcast()
 }
 
 var _ PubSub = (*PubSubSystem)(nil)
@@ -34,37 +80,153 @@ type PubSubSystem struct {
 	isClosed             bool
 	isClosedTopic        bool
 	topics               map[string]*PubSubSystem
-	messages             []interface{}
+	messages             []storedMessage
+	firstIndex           int
+	storedBytes          int
 	lastPublishedIndex   int
 	nextSubscriberID     int
+	subscribers          map[int]*Subscriber
+	queueGroups          map[string]*Subscriber
 	subscriberChannels   map[int]chan struct{}
 	unsubscribeChannels  map[int]chan struct{}
 	closeChannels        map[int]chan struct{}
 	notificationChannels map[int]chan struct{}
 	mutex                sync.RWMutex
+	spaceAvailable       *sync.Cond
+
+	retention      RetentionPolicy
+	topicRetention map[string]RetentionPolicy
+	closedSignal   chan struct{}
+	sweepThreshold int
+	errorHandler   func(err error)
+	panicPolicy    PanicPolicy
+
+	storageDir      string
+	durableSubjects map[string]bool
+	codec           Codec
+	maxSegmentBytes int64
+	stores          map[string]*topicStore
+	storesMutex     sync.Mutex
+	publishMutexes  map[string]*sync.Mutex
 }
 
-func NewPubSub() PubSub {
-	return &PubSubSystem{
+func NewPubSub(opts ...Option) PubSub {
+	ps := &PubSubSystem{
 		topics:               make(map[string]*PubSubSystem),
-		messages:             []interface{}{},
+		messages:             []storedMessage{},
+		lastPublishedIndex:   -1,
+		nextSubscriberID:     0,
+		subscribers:          make(map[int]*Subscriber),
+		queueGroups:          make(map[string]*Subscriber),
+		subscriberChannels:   make(map[int]chan struct{}),
+		unsubscribeChannels:  make(map[int]chan struct{}),
+		closeChannels:        make(map[int]chan struct{}),
+		notificationChannels: make(map[int]chan struct{}),
+		topicRetention:       make(map[string]RetentionPolicy),
+		closedSignal:         make(chan struct{}),
+		durableSubjects:      make(map[string]bool),
+		codec:                JSONCodec{},
+		maxSegmentBytes:      defaultMaxSegmentBytes,
+		stores:               make(map[string]*topicStore),
+		publishMutexes:       make(map[string]*sync.Mutex),
+	}
+
+	for _, opt := range opts {
+		opt(ps)
+	}
+
+	if maxAge := ps.shortestMaxAge(); maxAge > 0 {
+		go ps.expireMessages(maxAge / 2)
+	}
+
+	return ps
+}
+
+func (ps *PubSubSystem) newTopic(subject string) (*PubSubSystem, error) {
+	retention, ok := ps.topicRetention[subject]
+	if !ok {
+		retention = ps.retention
+	}
+
+	topic := &PubSubSystem{
+		messages:             []storedMessage{},
 		lastPublishedIndex:   -1,
 		nextSubscriberID:     0,
+		subscribers:          make(map[int]*Subscriber),
+		queueGroups:          make(map[string]*Subscriber),
 		subscriberChannels:   make(map[int]chan struct{}),
 		unsubscribeChannels:  make(map[int]chan struct{}),
 		closeChannels:        make(map[int]chan struct{}),
 		notificationChannels: make(map[int]chan struct{}),
+		retention:            retention,
+	}
+	topic.spaceAvailable = sync.NewCond(&topic.mutex)
+
+	// Positions in the topic of a durable subject continue its stored offsets
+	store, err := ps.store(subject)
+	if err != nil {
+		return nil, err
+	}
+	if store != nil {
+		topic.firstIndex = int(store.offset())
+		topic.lastPublishedIndex = topic.firstIndex - 1
 	}
+
+	return topic, nil
 }
 
 func (ps *PubSubSystem) Subscribe(subject string, handler MsgHandler) (Subscription, error) {
+	sub, err := ps.SubscribeWithOptions(subject, handler)
+	if err != nil {
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+// SubscribeWithOptions is Subscribe with per-subscription options.
+func (ps *PubSubSystem) SubscribeWithOptions(subject string, handler MsgHandler, opts ...SubscribeOption) (*Subscriber, error) {
+	return ps.subscribe(subject, "", handler, opts)
+}
+
+// QueueSubscribe subscribes handler as a member of a queue group. Each message
+// is delivered to exactly one member of every group, while plain subscribers
+// still receive all messages. Backpressure and start options apply to the
+// group as a whole and are taken from the member creating it.
+func (ps *PubSubSystem) QueueSubscribe(subject, group string, handler MsgHandler, opts ...SubscribeOption) (Subscription, error) {
+	if group == "" {
+		return nil, errors.New("empty queue group")
+	}
+
+	sub, err := ps.subscribe(subject, group, handler, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+func (ps *PubSubSystem) subscribe(subject, group string, handler MsgHandler, opts []SubscribeOption) (*Subscriber, error) {
+	// A durable subject's message is stored before it is delivered, so
+	// exclude its publishers to see the store and the topic agree
+	if publishMutex := ps.publishMutex(subject); publishMutex != nil {
+		publishMutex.Lock()
+		defer publishMutex.Unlock()
+	}
+
 	ps.mutex.Lock()
 	defer ps.mutex.Unlock()
 
 	if ps.isClosed {
-		return nil, errors.New("pubsub system is closed")
+		return nil, ErrClosed
+	}
+
+	if err := validateSubject(subject, true); err != nil {
+		return nil, err
 	}
 
+	ps.removeEmptyTopics()
+
 	closeChan := make(chan struct{})
 	unsubscribeChan := make(chan struct{})
 	notifyChan := make(chan struct{}, 1)
@@ -72,14 +234,10 @@ func (ps *PubSubSystem) Subscribe(subject string, handler MsgHandler) (Subscript
 
 	topic, exists := ps.topics[subject]
 	if !exists {
-		topic = &PubSubSystem{
-			messages:             []interface{}{},
-			lastPublishedIndex:   -1,
-			nextSubscriberID:     0,
-			subscriberChannels:   make(map[int]chan struct{}),
-			unsubscribeChannels:  make(map[int]chan struct{}),
-			closeChannels:        make(map[int]chan struct{}),
-			notificationChannels: make(map[int]chan struct{}),
+		var err error
+		topic, err = ps.newTopic(subject)
+		if err != nil {
+			return nil, err
 		}
 		ps.topics[subject] = topic
 	}
@@ -87,16 +245,9 @@ func (ps *PubSubSystem) Subscribe(subject string, handler MsgHandler) (Subscript
 	topic.mutex.Lock()
 	defer topic.mutex.Unlock()
 
-	subID := topic.nextSubscriberID
-	topic.nextSubscriberID++
-
-	topic.subscriberChannels[subID] = notifyChan
-	topic.unsubscribeChannels[subID] = unsubscribeChan
-	topic.closeChannels[subID] = closeChan
-	topic.notificationChannels[subID] = finishedChan
-
 	newSubscriber := &Subscriber{
-		id:                 subID,
+		id:                 topic.nextSubscriberID,
+		subject:            subject,
 		messageHandler:     handler,
 		lastProcessedIndex: topic.lastPublishedIndex,
 		topic:              topic,
@@ -104,6 +255,37 @@ func (ps *PubSubSystem) Subscribe(subject string, handler MsgHandler) (Subscript
 		unsubscribeSignal:  unsubscribeChan,
 		closeSignal:        closeChan,
 		finishedSignal:     finishedChan,
+		startOffset:        startAtNew,
+		codec:              ps.codec,
+		errorHandler:       ps.errorHandler,
+		panicPolicy:        ps.panicPolicy,
+	}
+	for _, opt := range opts {
+		opt(newSubscriber)
+	}
+
+	// Only the member creating a group replays, the others share its cursor
+	if _, exists := topic.queueGroups[group]; exists {
+		newSubscriber.startOffset = startAtNew
+	}
+
+	// Fail before the topic knows the subscriber, or Close would wait for it
+	if err := ps.prepareReplay(newSubscriber, subject); err != nil {
+		return nil, err
+	}
+
+	subID := newSubscriber.id
+	topic.nextSubscriberID++
+
+	topic.subscriberChannels[subID] = notifyChan
+	topic.unsubscribeChannels[subID] = unsubscribeChan
+	topic.closeChannels[subID] = closeChan
+	topic.notificationChannels[subID] = finishedChan
+
+	if group == "" {
+		topic.subscribers[subID] = newSubscriber
+	} else {
+		newSubscriber.group = topic.joinQueueGroup(group, opts)
 	}
 
 	go newSubscriber.listen()
@@ -112,94 +294,204 @@ func (ps *PubSubSystem) Subscribe(subject string, handler MsgHandler) (Subscript
 }
 
 func (sub *Subscriber) listen() {
+	if !sub.replay() {
+		sub.closeAfterPanic()
+		return
+	}
+
 	for {
 		select {
 		case <-sub.unsubscribeSignal:
 			return
 		case <-sub.notifySignal:
-			for {
-				sub.topic.mutex.RLock()
-				if sub.lastProcessedIndex == sub.topic.lastPublishedIndex {
-					sub.topic.mutex.RUnlock()
-					break
-				}
-				sub.lastProcessedIndex++
-				message := sub.topic.messages[sub.lastProcessedIndex]
-				sub.topic.mutex.RUnlock()
-
-				sub.messageHandler(message)
+			if !sub.processPending() {
+				sub.closeAfterPanic()
+				return
 			}
 		case <-sub.closeSignal:
-			for {
-				sub.topic.mutex.RLock()
-				if sub.lastProcessedIndex == sub.topic.lastPublishedIndex {
-					sub.topic.mutex.RUnlock()
-					break
-				}
-				sub.lastProcessedIndex++
-				message := sub.topic.messages[sub.lastProcessedIndex]
-				sub.topic.mutex.RUnlock()
-
-				sub.messageHandler(message)
-			}
+			sub.processPending()
 			sub.finishedSignal <- struct{}{}
 			return
 		}
 	}
 }
 
+// processPending handles the backlog. It returns false if a panicking
+// handler closed the subscription.
+func (sub *Subscriber) processPending() bool {
+	defer sub.topic.compact()
+
+	for {
+		message, ok := sub.nextMessage()
+		if !ok {
+			return true
+		}
+
+		handled := sub.handle(sub.delivered(message.message))
+
+		if sub.cursor().backpressure == BlockPublisher {
+			sub.topic.spaceAvailable.Broadcast()
+		}
+
+		if !handled {
+			return false
+		}
+	}
+}
+
+func (sub *Subscriber) nextMessage() (storedMessage, bool) {
+	sub.topic.mutex.RLock()
+	defer sub.topic.mutex.RUnlock()
+
+	// Members of a queue group compete for messages of the shared cursor
+	if sub.group != nil {
+		sub.group.cursorMutex.Lock()
+		defer sub.group.cursorMutex.Unlock()
+		sub = sub.group
+	}
+
+	// Skip messages dropped by the retention limits
+	if sub.lastProcessedIndex < sub.topic.firstIndex-1 {
+		missed := sub.topic.firstIndex - 1 - sub.lastProcessedIndex
+		missed -= sub.skippedBetween(sub.lastProcessedIndex, sub.topic.firstIndex-1)
+		sub.dropped.Add(uint64(missed))
+		sub.lastProcessedIndex = sub.topic.firstIndex - 1
+	}
+
+	for sub.lastProcessedIndex < sub.topic.lastPublishedIndex {
+		sub.lastProcessedIndex++
+
+		// Skip messages dropped by backpressure
+		if sub.isSkipped(sub.lastProcessedIndex) {
+			continue
+		}
+
+		return sub.topic.messages[sub.lastProcessedIndex-sub.topic.firstIndex], true
+	}
+
+	return storedMessage{}, false
+}
+
 func (ps *PubSubSystem) Publish(subject string, message interface{}) error {
+	_, err := ps.PublishWithHeaders(subject, message, nil)
+	return err
+}
+
+func (ps *PubSubSystem) publishMessage(message *Message) error {
+	// Messages of a durable subject reach the topics in stored order, taken
+	// before the system mutex so waiting publishers do not hold it
+	if publishMutex := ps.publishMutex(message.Subject); publishMutex != nil {
+		publishMutex.Lock()
+		defer publishMutex.Unlock()
+	}
+
+	topics, err := ps.matchingTopics(message)
+	if err != nil {
+		return err
+	}
+
+	// Deliver without holding the system mutex, a blocked publisher must not
+	// stall Subscribe, Close and publishers of other subjects
+	for _, topic := range topics {
+		topic.publish(message)
+	}
+
+	return nil
+}
+
+// matchingTopics persists the message and returns every topic whose pattern
+// matches its subject, publishing with no subscribers is not an error.
+func (ps *PubSubSystem) matchingTopics(message *Message) ([]*PubSubSystem, error) {
 	ps.mutex.RLock()
 	defer ps.mutex.RUnlock()
 
 	if ps.isClosed {
-		return errors.New("pubsub system is closed")
+		return nil, ErrClosed
 	}
 
-	topic, exists := ps.topics[subject]
-	if !exists {
-		return errors.New("topic does not exist")
+	if err := validateSubject(message.Subject, false); err != nil {
+		return nil, err
+	}
+
+	if err := ps.persist(message); err != nil {
+		return nil, err
 	}
 
+	var topics []*PubSubSystem
+	for pattern, topic := range ps.topics {
+		if matchSubject(pattern, message.Subject) {
+			topics = append(topics, topic)
+		}
+	}
+
+	return topics, nil
+}
+
+func (topic *PubSubSystem) publish(message *Message) {
 	topic.mutex.Lock()
 	defer topic.mutex.Unlock()
 
-	topic.messages = append(topic.messages, message)
+	// Subscribers of a closed topic are gone
+	if !topic.waitForBlockingSubscribers() {
+		return
+	}
+
+	// Every topic numbers the message on its own
+	envelope := *message
+	envelope.Sequence = int64(topic.lastPublishedIndex) + 2
+
+	stored := topic.newStoredMessage(&envelope)
+	topic.messages = append(topic.messages, stored)
+	topic.storedBytes += stored.size
 	topic.lastPublishedIndex++
 
+	for _, sub := range topic.subscribers {
+		sub.applyBackpressure()
+	}
+	topic.compactLocked()
+
 	for _, notifyChan := range topic.subscriberChannels {
 		select {
 		case notifyChan <- struct{}{}:
 		default:
 		}
 	}
-
-	return nil
 }
 
+// Close waits for handlers to process the published messages. Closing again
+// does nothing.
 func (ps *PubSubSystem) Close(ctx context.Context) error {
 	ps.mutex.Lock()
 	defer ps.mutex.Unlock()
 
+	if ps.isClosed {
+		return nil
+	}
+	close(ps.closedSignal)
 	ps.isClosed = true
 	for _, topic := range ps.topics {
 		topic.mutex.Lock()
 		topic.isClosedTopic = true
 		topic.mutex.Unlock()
+		topic.spaceAvailable.Broadcast()
 
+		// Subscribers closed by a panic leave concurrently, so wait for a
+		// snapshot of the ones signaled
 		topic.mutex.RLock()
+		var finishedChannels []chan struct{}
 		for id := range topic.subscriberChannels {
 			close(topic.closeChannels[id])
+			finishedChannels = append(finishedChannels, topic.notificationChannels[id])
 		}
 		topic.mutex.RUnlock()
 
-		for id := range topic.subscriberChannels {
+		for _, finishedChan := range finishedChannels {
 			select {
 			case <-ctx.Done():
-			case <-topic.notificationChannels[id]:
+			case <-finishedChan:
 			}
 		}
 	}
 
-	return nil
+	return ps.closeStores()
 }